@@ -10,6 +10,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -45,6 +46,13 @@ func init() {
 	slaxyCmd.PersistentFlags().StringP("channel", "n", "", "slack channel")
 	slaxyCmd.PersistentFlags().StringP("discord-webhook-url", "u", "", "discord webhook url")
 	slaxyCmd.PersistentFlags().StringSliceP("excluded-fields", "e", nil, "excluded sentry fields")
+	slaxyCmd.PersistentFlags().StringP("client-secret", "s", "", "sentry integration client secret")
+	slaxyCmd.PersistentFlags().Bool("slack-threads", false, "post follow-up events of an issue as thread replies")
+	slaxyCmd.PersistentFlags().Bool("slack-thread-broadcast", false, "also send thread replies to the channel")
+	slaxyCmd.PersistentFlags().Bool("slack-update-messages", false, "update the first message of an issue when it gets resolved or regresses")
+	slaxyCmd.PersistentFlags().String("store-path", "", "path of the on-disk store, kept in memory if empty")
+	slaxyCmd.PersistentFlags().Duration("store-ttl", 30*24*time.Hour, "how long issue to message mappings are kept")
+	slaxyCmd.PersistentFlags().String("telegram-token", "", "telegram bot token")
 
 	_ = v.BindPFlag("grace-period", slaxyCmd.PersistentFlags().Lookup("grace-period"))
 	_ = v.BindPFlag("addr", slaxyCmd.PersistentFlags().Lookup("addr"))
@@ -52,6 +60,13 @@ func init() {
 	_ = v.BindPFlag("channel", slaxyCmd.PersistentFlags().Lookup("channel"))
 	_ = v.BindPFlag("discord-webhook-url", slaxyCmd.PersistentFlags().Lookup("discord-webhook-url"))
 	_ = v.BindPFlag("excluded-fields", slaxyCmd.PersistentFlags().Lookup("excluded-fields"))
+	_ = v.BindPFlag("client-secret", slaxyCmd.PersistentFlags().Lookup("client-secret"))
+	_ = v.BindPFlag("slack-threads", slaxyCmd.PersistentFlags().Lookup("slack-threads"))
+	_ = v.BindPFlag("slack-thread-broadcast", slaxyCmd.PersistentFlags().Lookup("slack-thread-broadcast"))
+	_ = v.BindPFlag("slack-update-messages", slaxyCmd.PersistentFlags().Lookup("slack-update-messages"))
+	_ = v.BindPFlag("store-path", slaxyCmd.PersistentFlags().Lookup("store-path"))
+	_ = v.BindPFlag("store-ttl", slaxyCmd.PersistentFlags().Lookup("store-ttl"))
+	_ = v.BindPFlag("telegram-token", slaxyCmd.PersistentFlags().Lookup("telegram-token"))
 }
 
 func main() {
@@ -123,7 +138,12 @@ func loadConfig() {
 	if err != nil {
 		logger.WithError(err).Fatal("Could not parse config")
 	}
-	logger.WithField("config", cfg).Info("config loaded")
+	// the config holds tokens and secrets, so only log where it came from
+	logger.WithFields(logrus.Fields{
+		"file":       v.ConfigFileUsed(),
+		"addr":       cfg.Addr,
+		"store-path": cfg.StorePath,
+	}).Info("config loaded")
 }
 
 // handleInterrupt takes care of signals and graceful shutdowns
@@ -0,0 +1,330 @@
+package slaxy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CodeOwnersConfig routes alerts to the owners of the code they were raised in and mentions them
+type CodeOwnersConfig struct {
+	// Path is a CODEOWNERS file, each line is a path glob followed by its owners: "@org/team" handles,
+	// emails or destinations like "slack:C0123456789", the last matching line wins
+	Path string `mapstructure:"path"`
+	// ReloadInterval is how often the file is checked for changes, 30s if empty
+	ReloadInterval time.Duration `mapstructure:"reload-interval"`
+	// Owners are the destinations and sentry teams of the handles of the file
+	Owners []CodeOwner `mapstructure:"owners"`
+}
+
+// CodeOwner is a handle of the CODEOWNERS file
+type CodeOwner struct {
+	// Owner is the handle, eg: "@acme/payments"
+	Owner string `mapstructure:"owner"`
+	// Destinations the alerts of the code of the owner are sent to
+	Destinations []string `mapstructure:"destinations"`
+	// Team is the slug of the sentry team which is mentioned, the name of a team handle if empty
+	Team string `mapstructure:"team"`
+}
+
+// codeOwner is an owner of a CODEOWNERS line
+type codeOwner struct {
+	destinations []destination
+	owner
+}
+
+// codeOwnersRule is a compiled CODEOWNERS line
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []codeOwner
+}
+
+// codeOwners are the rules of the CODEOWNERS file, reloaded when it changes
+type codeOwners struct {
+	path     string
+	handles  map[string]codeOwner
+	validate func(d destination) error
+
+	mu      sync.RWMutex
+	rules   []codeOwnersRule
+	modTime time.Time
+	size    int64
+}
+
+// newCodeOwners loads the CODEOWNERS file, it is nil without a path
+func newCodeOwners(cfg CodeOwnersConfig, validate func(d destination) error) (*codeOwners, error) {
+	if cfg.Path == "" {
+		return nil, nil
+	}
+
+	c := &codeOwners{path: cfg.Path, handles: make(map[string]codeOwner, len(cfg.Owners)), validate: validate}
+	for _, o := range cfg.Owners {
+		destinations, err := parseDestinations(o.Destinations)
+		if err != nil {
+			return nil, fmt.Errorf("invalid code owner %s, err: %w", o.Owner, err)
+		}
+		for _, d := range destinations {
+			if err := validate(d); err != nil {
+				return nil, fmt.Errorf("invalid code owner %s, err: %w", o.Owner, err)
+			}
+		}
+
+		team := o.Team
+		if team == "" {
+			team = handleTeam(o.Owner)
+		}
+		c.handles[strings.ToLower(o.Owner)] = codeOwner{destinations: destinations, owner: owner{team: team}}
+	}
+
+	if _, err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// reload parses the file again if it changed since it was loaded
+func (c *codeOwners) reload() (bool, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read code owners, err: %w", err)
+	}
+
+	c.mu.RLock()
+	changed := !info.ModTime().Equal(c.modTime) || info.Size() != c.size
+	c.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	rules, err := c.parse()
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.rules = rules
+	c.modTime = info.ModTime()
+	c.size = info.Size()
+	c.mu.Unlock()
+
+	return true, nil
+}
+
+// parse parses the lines of the file
+func (c *codeOwners) parse() ([]codeOwnersRule, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code owners, err: %w", err)
+	}
+	defer f.Close()
+
+	var rules []codeOwnersRule
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		pattern, err := compileCodeOwnersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in line %d of %s, err: %w", n, c.path, err)
+		}
+
+		// lines without owners make the files unowned
+		rule := codeOwnersRule{pattern: pattern}
+		for _, field := range fields[1:] {
+			o, err := c.owner(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid owner in line %d of %s, err: %w", n, c.path, err)
+			}
+			rule.owners = append(rule.owners, o)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read code owners, err: %w", err)
+	}
+
+	return rules, nil
+}
+
+// owner resolves an owner of a line, eg: "@acme/payments", "jane@acme.com" or "slack:C0123456789"
+func (c *codeOwners) owner(field string) (codeOwner, error) {
+	if o, ok := c.handles[strings.ToLower(field)]; ok {
+		return o, nil
+	}
+
+	switch {
+	case strings.HasPrefix(field, "@"):
+		// user handles can't be mentioned without their email
+		return codeOwner{owner: owner{team: handleTeam(field)}}, nil
+	case strings.Contains(field, "@"):
+		return codeOwner{owner: owner{email: field}}, nil
+	case strings.Contains(field, ":"):
+		d, err := parseDestination(field)
+		if err != nil {
+			return codeOwner{}, err
+		}
+		if err := c.validate(d); err != nil {
+			return codeOwner{}, err
+		}
+		return codeOwner{destinations: []destination{d}}, nil
+	default:
+		return codeOwner{}, fmt.Errorf("unknown owner %q", field)
+	}
+}
+
+// handleTeam returns the team name of a team handle like "@acme/payments", it is empty for user handles
+func handleTeam(handle string) string {
+	_, team, ok := strings.Cut(strings.TrimPrefix(handle, "@"), "/")
+	if !ok {
+		return ""
+	}
+
+	return team
+}
+
+// compileCodeOwnersPattern compiles a gitignore style pattern, patterns with a slash at the start or in the middle
+// are relative to the root, others match in any directory, all match the files below the matching directories
+func compileCodeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("(/.*)?$")
+
+	return regexp.Compile(expr.String())
+}
+
+// match returns the owners of the first in-app file of the stacktrace, the innermost frame first,
+// or of the file of the event
+func (c *codeOwners) match(hook *Webhook) []codeOwner {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, path := range stacktracePaths(hook) {
+		if owners := c.ownersOf(path); len(owners) > 0 {
+			return owners
+		}
+	}
+
+	return nil
+}
+
+// ownersOf returns the owners of the last rule matching the path, deployed paths like "/srv/app/src/main.go"
+// are matched with their leading directories stripped in turn
+func (c *codeOwners) ownersOf(path string) []codeOwner {
+	path = strings.Trim(strings.ReplaceAll(path, `\`, "/"), "/")
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		for suffix := path; suffix != ""; {
+			if c.rules[i].pattern.MatchString(suffix) {
+				return c.rules[i].owners
+			}
+
+			_, rest, ok := strings.Cut(suffix, "/")
+			if !ok {
+				break
+			}
+			suffix = rest
+		}
+	}
+
+	return nil
+}
+
+// stacktracePaths returns the paths of the in-app frames of the latest exception first, innermost frame first,
+// and the file of the event
+func stacktracePaths(hook *Webhook) []string {
+	var paths []string
+	values := hook.Event.Exception.Values
+	for i := len(values) - 1; i >= 0; i-- {
+		frames := values[i].Stacktrace.Frames
+		for j := len(frames) - 1; j >= 0; j-- {
+			if !frames[j].InApp {
+				continue
+			}
+			for _, path := range []string{frames[j].Filename, frames[j].AbsPath} {
+				if path != "" {
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	if hook.Event.Metadata.Filename != "" {
+		paths = append(paths, hook.Event.Metadata.Filename)
+	}
+
+	return paths
+}
+
+// codeOwnerDestinations returns the destinations of the code owners of the hook
+func (s *server) codeOwnerDestinations(hook *Webhook) []destination {
+	if s.codeOwners == nil {
+		return nil
+	}
+
+	var destinations []destination
+	for _, o := range s.codeOwners.match(hook) {
+		destinations = append(destinations, o.destinations...)
+	}
+
+	return destinations
+}
+
+// reloadCodeOwners reloads the CODEOWNERS file when it changes until the server is stopped
+func (s *server) reloadCodeOwners() {
+	interval := s.cfg.CodeOwners.ReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			// keep the rules loaded last if the file is broken
+			changed, err := s.codeOwners.reload()
+			if err != nil {
+				s.logger.Errorf("failed to reload code owners: %s", err)
+				continue
+			}
+			if changed {
+				s.logger.Infof("Reloaded code owners from %s", s.codeOwners.path)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package slaxy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCodeOwnersPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*", "main.go", true},
+		{"*.go", "internal/payments/charge.go", true},
+		{"*.go", "main.py", false},
+		{"/src/payments/", "src/payments/charge.go", true},
+		{"/src/payments/", "lib/src/payments/charge.go", false},
+		{"docs/", "api/docs/index.md", true},
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/payments/charge.go", false},
+		{"src/**/charge.go", "src/payments/v2/charge.go", true},
+		{"**/payments", "internal/payments/charge.go", true},
+		{"/Makefile", "src/Makefile", false},
+	}
+
+	for _, test := range tests {
+		pattern, err := compileCodeOwnersPattern(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pattern.MatchString(test.path); got != test.want {
+			t.Errorf("%s %s: expected %v, got %v", test.pattern, test.path, test.want, got)
+		}
+	}
+}
+
+func TestCodeOwners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CODEOWNERS")
+	writeFile := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(`# default owners
+*                 @acme/platform
+/src/payments/    @acme/payments jane@acme.com
+/src/payments/legacy/
+`)
+
+	s := New(Config{
+		CodeOwners: CodeOwnersConfig{
+			Path: path,
+			Owners: []CodeOwner{
+				{Owner: "@acme/payments", Destinations: []string{"slack:C0PAYMENTS"}},
+				{Owner: "@ACME/platform", Destinations: []string{"discord:platform"}, Team: "infra"},
+			},
+		},
+		Mentions: MentionConfig{
+			Users: []MentionUser{{Email: "jane@acme.com", Slack: "U0123456789"}},
+			Teams: []MentionTeam{{Team: "payments", SlackGroup: "S0123456789"}},
+		},
+	}, NewNullLogger()).(*server)
+
+	var err error
+	s.codeOwners, err = newCodeOwners(s.cfg.CodeOwners, func(d destination) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := func(frames ...StacktraceFrame) *Webhook {
+		return &Webhook{ID: "1", Event: SentryEvent{Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: frames}}}}}}
+	}
+	payments := hook(
+		StacktraceFrame{AbsPath: "/srv/app/src/payments/charge.go", InApp: true},
+		StacktraceFrame{AbsPath: "/go/pkg/mod/github.com/lib/pq/conn.go"},
+	)
+
+	// the owners get the alert besides the channel of the webhook path
+	s.defaultDestinations = []destination{{kind: destinationSlack}}
+	path0 := destination{kind: destinationSlack, target: "C0ALERTS"}
+	if got, want := s.route(payments, path0), []destination{path0, {kind: destinationSlack, target: "C0PAYMENTS"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the alerts and payments channels, got %v", got)
+	}
+	if got := joinMentions(s.slackMentions(context.Background(), payments)...); got != "<!subteam^S0123456789> <@U0123456789>" {
+		t.Errorf("unexpected mentions %q", got)
+	}
+
+	// the innermost owned in-app frame counts
+	legacy := hook(
+		StacktraceFrame{Filename: "cmd/server/main.go", InApp: true},
+		StacktraceFrame{Filename: "src/payments/legacy/refund.go", InApp: true},
+	)
+	if got, want := s.route(legacy, path0), []destination{path0, {kind: destinationDiscord, target: "platform"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the alerts channel and the platform webhook, got %v", got)
+	}
+	if owners := s.owners(context.Background(), legacy); len(owners) != 1 || owners[0].team != "infra" {
+		t.Errorf("expected the configured team, got %+v", owners)
+	}
+
+	// the file is reloaded when it changes
+	writeFile("/src/ payments:oncall\n")
+	if changed, err := s.codeOwners.reload(); err != nil || !changed {
+		t.Fatalf("expected a reload, got %v %v", changed, err)
+	}
+	if got, want := s.route(payments, path0), []destination{path0, {kind: "payments", target: "oncall"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the reloaded owners, got %v", got)
+	}
+	if changed, err := s.codeOwners.reload(); err != nil || changed {
+		t.Errorf("expected no reload of the unchanged file, got %v %v", changed, err)
+	}
+
+	writeFile("/src/ not-an-owner\n")
+	if _, err := s.codeOwners.reload(); err == nil {
+		t.Error("expected an error of the invalid owner")
+	}
+	if got := s.route(payments, path0); len(got) != 2 || got[1].kind != "payments" {
+		t.Errorf("expected the last valid owners to be kept, got %v", got)
+	}
+}
@@ -9,6 +9,7 @@ require (
 	github.com/slack-go/slack v0.13.0
 	github.com/spf13/cobra v1.3.0
 	github.com/spf13/viper v1.10.1
+	go.etcd.io/bbolt v1.3.10
 )
 
 require (
@@ -0,0 +1,319 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// MentionConfig maps the sentry users and teams an alert belongs to onto slack and discord mentions,
+// they are lists as emails can't be config keys
+type MentionConfig struct {
+	Users []MentionUser `mapstructure:"users"`
+	Teams []MentionTeam `mapstructure:"teams"`
+	// SlackLookup looks up the slack users of the other emails with users.lookupByEmail,
+	// it needs the users:read.email scope
+	SlackLookup bool `mapstructure:"slack-lookup"`
+	// CacheTTL is how long the looked up slack users are kept, 24h if empty
+	CacheTTL time.Duration `mapstructure:"cache-ttl"`
+	// SentryToken is a sentry auth token with the event:read scope, it looks up the assignee and the suspect
+	// commit authors of event alerts of the integration platform, their payloads carry neither.
+	// Only signed webhooks are looked up
+	SentryToken string `mapstructure:"sentry-token"`
+	// SentryURL is the url of the sentry the token belongs to, https://sentry.io if empty
+	SentryURL string `mapstructure:"sentry-url"`
+}
+
+// defaultSentryURL is the url of the sentry api if none is configured
+const defaultSentryURL = "https://sentry.io"
+
+// sentryOwnersTTL is how long the owners of an issue looked up in sentry are kept
+const sentryOwnersTTL = 10 * time.Minute
+
+// sentryCommitters is the response of the committers api of an event, the authors of its suspect commits
+type sentryCommitters struct {
+	Committers []struct {
+		Author struct {
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"committers"`
+}
+
+// MentionUser is the slack and discord user of the email of a sentry user
+type MentionUser struct {
+	Email string `mapstructure:"email"`
+	// Slack is the slack user id, eg: "U0123456789", it takes precedence over the lookup
+	Slack   string `mapstructure:"slack"`
+	Discord string `mapstructure:"discord"`
+}
+
+// MentionTeam is the slack user group and discord role of a sentry team
+type MentionTeam struct {
+	// Team is the slug of the sentry team
+	Team string `mapstructure:"team"`
+	// SlackGroup is the slack user group id, eg: "S0123456789"
+	SlackGroup  string `mapstructure:"slack-group"`
+	DiscordRole string `mapstructure:"discord-role"`
+}
+
+// mentionUser returns the configured user of the email
+func (c *MentionConfig) mentionUser(email string) MentionUser {
+	for _, user := range c.Users {
+		if strings.EqualFold(user.Email, email) {
+			return user
+		}
+	}
+
+	return MentionUser{}
+}
+
+// mentionTeam returns the configured team of the slug
+func (c *MentionConfig) mentionTeam(slug string) MentionTeam {
+	for _, team := range c.Teams {
+		if strings.EqualFold(team.Team, slug) {
+			return team
+		}
+	}
+
+	return MentionTeam{}
+}
+
+// owner is a sentry user or team an alert belongs to
+type owner struct {
+	email string
+	team  string
+}
+
+// assigneeOwner returns the owner of the assignee of an issue
+func assigneeOwner(assignee *SentryAssignee) (owner, bool) {
+	switch {
+	case assignee == nil:
+		return owner{}, false
+	case assignee.Type == "team" && assignee.Name != "":
+		return owner{team: assignee.Name}, true
+	case assignee.Email != "":
+		return owner{email: assignee.Email}, true
+	default:
+		return owner{}, false
+	}
+}
+
+// owners returns the users and teams which are mentioned for the hook, the assignee of new alerts
+// and of assignments, the suspect commit authors of event alerts and the code owners of new alerts
+func (s *server) owners(ctx context.Context, hook *Webhook) []owner {
+	var owners []owner
+	if hook.Issue != nil && (hook.isNewAlert() || hook.Action == issueAssigned) {
+		if o, ok := assigneeOwner(hook.Issue.AssignedTo); ok {
+			owners = append(owners, o)
+		}
+	}
+
+	if hook.isNewAlert() {
+		owners = append(owners, s.sentryOwners(ctx, hook)...)
+	}
+
+	if s.codeOwners != nil && hook.isNewAlert() {
+		for _, o := range s.codeOwners.match(hook) {
+			if o.owner != (owner{}) {
+				owners = append(owners, o.owner)
+			}
+		}
+	}
+
+	return owners
+}
+
+// slackMentions returns the slack mentions of the owners of the hook, eg: "<@U0123456789>"
+func (s *server) slackMentions(ctx context.Context, hook *Webhook) []string {
+	var mentions []string
+	for _, o := range s.owners(ctx, hook) {
+		if o.team != "" {
+			if id := s.cfg.Mentions.mentionTeam(o.team).SlackGroup; id != "" {
+				mentions = append(mentions, "<!subteam^"+id+">")
+			}
+			continue
+		}
+
+		if id := s.slackUserID(ctx, o.email); id != "" {
+			mentions = append(mentions, "<@"+id+">")
+		}
+	}
+
+	return mentions
+}
+
+// slackUserID returns the id of the slack user of the email, the lookups are cached in the store
+func (s *server) slackUserID(ctx context.Context, email string) string {
+	if id := s.cfg.Mentions.mentionUser(email).Slack; id != "" {
+		return id
+	}
+	if !s.cfg.Mentions.SlackLookup || s.slack == nil {
+		return ""
+	}
+
+	key := "slack-user:" + strings.ToLower(email)
+	if id, ok, err := s.store.Get(key); err != nil {
+		s.logger.Warnf("failed to load the slack user of %s: %s", email, err)
+	} else if ok {
+		return id
+	}
+
+	// emails without a slack user are cached as well
+	id := ""
+	user, err := s.slack.GetUserByEmailContext(ctx, email)
+	var slackErr slack.SlackErrorResponse
+	switch {
+	case err == nil:
+		id = user.ID
+	case errors.As(err, &slackErr) && slackErr.Err == "users_not_found":
+	default:
+		s.logger.Warnf("failed to look up the slack user of %s: %s", email, err)
+		return ""
+	}
+
+	ttl := s.cfg.Mentions.CacheTTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	if err := s.store.Set(key, id, ttl); err != nil {
+		s.logger.Warnf("failed to save the slack user of %s: %s", email, err)
+	}
+
+	return id
+}
+
+// sentryOwners looks up the assignee and the suspect commit authors of an event alert in sentry,
+// they are cached in the store for a while as every destination asks for them. The token is only
+// sent to the configured sentry and for signed webhooks, anyone could post the others
+func (s *server) sentryOwners(ctx context.Context, hook *Webhook) []owner {
+	if s.cfg.Mentions.SentryToken == "" || !hook.signed || hook.Resource != resourceEventAlert || hook.ID == "" {
+		return nil
+	}
+
+	// owners are cached as "team:<slug>" and "email:<address>"
+	key := "sentry-owners:" + hook.issueKey()
+	if value, ok, err := s.store.Get(key); err != nil {
+		s.logger.Warnf("failed to load the sentry owners of %s: %s", hook.issueKey(), err)
+	} else if ok {
+		var owners []owner
+		for _, o := range strings.Fields(value) {
+			kind, name, _ := strings.Cut(o, ":")
+			if kind == "team" {
+				owners = append(owners, owner{team: name})
+			} else {
+				owners = append(owners, owner{email: name})
+			}
+		}
+		return owners
+	}
+
+	var owners []owner
+	var issue SentryIssue
+	if err := s.sentryGet(ctx, s.sentryAPIURL("issues", hook.ID), &issue); err != nil {
+		s.logger.Warnf("failed to look up the assignee of %s: %s", hook.issueKey(), err)
+		return nil
+	}
+	if o, ok := assigneeOwner(issue.AssignedTo); ok {
+		owners = append(owners, o)
+	}
+	if hook.organization != "" && hook.ProjectSlug != "" && hook.Event.EventID != "" {
+		// events without suspect commits have no committers
+		var committers sentryCommitters
+		apiURL := s.sentryAPIURL("projects", hook.organization, hook.ProjectSlug, "events", hook.Event.EventID, "committers")
+		if err := s.sentryGet(ctx, apiURL, &committers); err != nil {
+			s.logger.Warnf("failed to look up the suspect commits of %s: %s", hook.issueKey(), err)
+			return nil
+		}
+		for _, c := range committers.Committers {
+			if c.Author.Email != "" {
+				owners = append(owners, owner{email: c.Author.Email})
+			}
+		}
+	}
+
+	cached := make([]string, 0, len(owners))
+	for _, o := range owners {
+		if o.team != "" {
+			cached = append(cached, "team:"+o.team)
+		} else {
+			cached = append(cached, "email:"+o.email)
+		}
+	}
+	if err := s.store.Set(key, strings.Join(cached, " "), sentryOwnersTTL); err != nil {
+		s.logger.Warnf("failed to save the sentry owners of %s: %s", hook.issueKey(), err)
+	}
+
+	return owners
+}
+
+// sentryAPIURL returns the url of the sentry api endpoint of the path segments, eg: "issues", "42"
+func (s *server) sentryAPIURL(segments ...string) string {
+	base := s.cfg.Mentions.SentryURL
+	if base == "" {
+		base = defaultSentryURL
+	}
+
+	escaped := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+
+	return strings.TrimSuffix(base, "/") + "/api/0/" + strings.Join(escaped, "/") + "/"
+}
+
+// sentryGet decodes the response of the sentry api url into result, it is left empty if nothing was found
+func (s *server) sentryGet(ctx context.Context, apiURL string, result interface{}) error {
+	res, err := s.client.R().SetContext(ctx).SetAuthToken(s.cfg.Mentions.SentryToken).Get(apiURL)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode() == http.StatusNotFound {
+		return nil
+	}
+	if res.StatusCode() >= 300 {
+		return fmt.Errorf("status=%d, response_body=%s", res.StatusCode(), res.Body())
+	}
+
+	return json.Unmarshal(res.Body(), result)
+}
+
+// discordMentions returns the discord mentions of the owners of the hook, eg: "<@123456789>"
+func (s *server) discordMentions(ctx context.Context, hook *Webhook) []string {
+	var mentions []string
+	for _, o := range s.owners(ctx, hook) {
+		if o.team != "" {
+			if id := s.cfg.Mentions.mentionTeam(o.team).DiscordRole; id != "" {
+				mentions = append(mentions, "<@&"+id+">")
+			}
+			continue
+		}
+
+		if id := s.cfg.Mentions.mentionUser(o.email).Discord; id != "" {
+			mentions = append(mentions, "<@"+id+">")
+		}
+	}
+
+	return mentions
+}
+
+// joinMentions joins the mentions without empty and repeated ones
+func joinMentions(mentions ...string) string {
+	seen := make(map[string]bool, len(mentions))
+	unique := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		if mention == "" || seen[mention] {
+			continue
+		}
+		seen[mention] = true
+		unique = append(unique, mention)
+	}
+
+	return strings.Join(unique, " ")
+}
@@ -0,0 +1,187 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	fake := newFakeSlack(t)
+	fake.responses = map[string]string{
+		"users.lookupByEmail": `{"ok": true, "user": {"id": "U0000000042"}}`,
+	}
+
+	s := New(Config{Mentions: MentionConfig{
+		Users:       []MentionUser{{Email: "Jane@acme.com", Slack: "U0123456789", Discord: "123456789"}},
+		Teams:       []MentionTeam{{Team: "backend", SlackGroup: "S0123456789", DiscordRole: "987654321"}},
+		SlackLookup: true,
+	}}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	assigned := func(assignee SentryAssignee) *Webhook {
+		return &Webhook{
+			ID: "1", Resource: resourceIssue, Action: issueAssigned,
+			Issue: &SentryIssue{AssignedTo: &assignee}, Event: SentryEvent{Title: "boom"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		hook    *Webhook
+		slack   string
+		discord string
+	}{
+		{"configured user", assigned(SentryAssignee{Type: "user", Email: "jane@acme.com"}), "<@U0123456789>", "<@123456789>"},
+		{"looked up user", assigned(SentryAssignee{Type: "user", Email: "john@acme.com"}), "<@U0000000042>", ""},
+		{"team", assigned(SentryAssignee{Type: "team", Name: "backend"}), "<!subteam^S0123456789>", "<@&987654321>"},
+		{"unknown team", assigned(SentryAssignee{Type: "team", Name: "frontend"}), "", ""},
+		{"unassigned", &Webhook{ID: "1", Event: SentryEvent{Title: "boom"}}, "", ""},
+	}
+	for _, test := range tests {
+		if got := joinMentions(s.slackMentions(context.Background(), test.hook)...); got != test.slack {
+			t.Errorf("%s: expected slack mention %q, got %q", test.name, test.slack, got)
+		}
+		if got := s.renderDiscordMessage(context.Background(), test.hook, nil, true).Content; got != test.discord {
+			t.Errorf("%s: expected discord mention %q, got %q", test.name, test.discord, got)
+		}
+	}
+
+	// the lookup is cached
+	s.slackMentions(context.Background(), tests[1].hook)
+	lookups := 0
+	for _, call := range fake.calls {
+		if call.Method == "users.lookupByEmail" {
+			lookups++
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected a single lookup, got %d", lookups)
+	}
+
+	// resolutions don't mention the assignee
+	resolved := assigned(SentryAssignee{Type: "user", Email: "jane@acme.com"})
+	resolved.Action = issueResolved
+	if got := s.slackMentions(context.Background(), resolved); len(got) != 0 {
+		t.Errorf("expected no mentions of the resolution, got %v", got)
+	}
+}
+
+func TestSlackUserNotFound(t *testing.T) {
+	fake := newFakeSlack(t)
+	fake.responses = map[string]string{
+		"users.lookupByEmail": `{"ok": false, "error": "users_not_found"}`,
+	}
+
+	s := New(Config{Mentions: MentionConfig{SlackLookup: true}}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	for i := 0; i < 2; i++ {
+		if id := s.slackUserID(context.Background(), "nobody@acme.com"); id != "" {
+			t.Errorf("expected no user, got %s", id)
+		}
+	}
+	if len(fake.calls) != 1 {
+		t.Errorf("expected the missing user to be cached, got %d lookups", len(fake.calls))
+	}
+}
+
+func TestEventAlertMentions(t *testing.T) {
+	var requests []string
+	sentry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sntrys_test" {
+			t.Errorf("unexpected auth %q", r.Header.Get("Authorization"))
+		}
+		requests = append(requests, r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/0/issues/1117540176/":
+			fmt.Fprint(w, `{"id": "1117540176", "assignedTo": {"type": "team", "name": "backend"}}`)
+		case "/api/0/projects/acme/backend/events/fec9f96296cb47d89e652d183e2752cf/committers/":
+			fmt.Fprint(w, `{"committers": [{"author": {"email": "jane@acme.com"}, "commits": [{"id": "abc"}]}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer sentry.Close()
+
+	// the token must never be sent to the api urls of the payload, anyone can post one
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("the foreign host was called with %s %q", r.URL.Path, r.Header.Get("Authorization"))
+	}))
+	defer foreign.Close()
+
+	payload := fmt.Sprintf(`{"action": "triggered", "data": {"event": {
+		"title": "boom", "level": "error", "issue_id": "1117540176", "event_id": "fec9f96296cb47d89e652d183e2752cf",
+		"url": "%[1]s/api/0/projects/acme/backend/events/fec9f96296cb47d89e652d183e2752cf/",
+		"issue_url": "%[1]s/api/0/issues/1117540176/"
+	}, "triggered_rule": "new issues"}}`, foreign.URL)
+	hook, err := parseWebhook(resourceEventAlert, []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{Mentions: MentionConfig{
+		Users:       []MentionUser{{Email: "jane@acme.com", Slack: "U0123456789", Discord: "123456789"}},
+		Teams:       []MentionTeam{{Team: "backend", SlackGroup: "S0123456789", DiscordRole: "987654321"}},
+		SentryToken: "sntrys_test",
+		SentryURL:   sentry.URL + "/",
+	}}
+
+	// unsigned webhooks could come from anyone
+	s := New(cfg, NewNullLogger()).(*server)
+	if got := s.slackMentions(context.Background(), hook); len(got) != 0 || len(requests) != 0 {
+		t.Errorf("unsigned webhooks should not be looked up, got %v and requests %v", got, requests)
+	}
+
+	hook.signed = true
+	s = New(cfg, NewNullLogger()).(*server)
+	if got := joinMentions(s.slackMentions(context.Background(), hook)...); got != "<!subteam^S0123456789> <@U0123456789>" {
+		t.Errorf("expected the assignee and the suspect commit author to be mentioned, got %q", got)
+	}
+	if got := s.renderDiscordMessage(context.Background(), hook, nil, true).Content; got != "<@&987654321> <@123456789>" {
+		t.Errorf("expected the assignee and the suspect commit author to be mentioned, got %q", got)
+	}
+
+	// the owners are looked up once for all destinations
+	if len(requests) != 2 {
+		t.Errorf("expected two sentry requests, got %v", requests)
+	}
+}
+
+func TestOwnersMentionedOnlyOnFirstAlert(t *testing.T) {
+	fake := newFakeSlack(t)
+	s := New(Config{SlackThreads: true, Mentions: MentionConfig{
+		Users: []MentionUser{{Email: "jane@acme.com", Slack: "U0123456789", Discord: "123456789"}},
+	}}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	jane := &SentryAssignee{Type: "user", Email: "jane@acme.com"}
+	event := &Webhook{ID: "1", Resource: resourceIssue, Action: issueCreated, Issue: &SentryIssue{AssignedTo: jane}, Event: SentryEvent{Title: "boom"}}
+	assigned := &Webhook{ID: "1", Resource: resourceIssue, Action: issueAssigned, Issue: &SentryIssue{AssignedTo: jane}, Event: SentryEvent{Title: "boom"}}
+	for _, hook := range []*Webhook{event, event, assigned} {
+		if err := s.slackHandleHook(context.Background(), hook, "alerts", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := fake.call(0).Form.Get("text"); got != "<@U0123456789>" {
+		t.Errorf("expected the first alert to mention the assignee, got %q", got)
+	}
+	if got := fake.call(1).Form.Get("text"); got != "" {
+		t.Errorf("expected the repeated alert in the thread to stay quiet, got %q", got)
+	}
+	if got := fake.call(2).Form.Get("text"); got != "<@U0123456789>" {
+		t.Errorf("expected the assignment to mention the assignee, got %q", got)
+	}
+
+	discord := destination{kind: destinationDiscord, target: "ops"}
+	first := s.renderDiscordMessage(context.Background(), event, nil, s.isFirstAlert(event, discord))
+	repeated := s.renderDiscordMessage(context.Background(), event, nil, s.isFirstAlert(event, discord))
+	if first.Content != "<@123456789>" || repeated.Content != "" {
+		t.Errorf("expected only the first discord message to mention, got %q and %q", first.Content, repeated.Content)
+	}
+}
@@ -0,0 +1,52 @@
+package slaxy
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// metrics holds simple counters which are exposed in the prometheus text format
+type metrics struct {
+	mu       sync.Mutex
+	counters map[string]uint64
+}
+
+// newMetrics creates an empty metrics registry
+func newMetrics() *metrics {
+	return &metrics{
+		counters: make(map[string]uint64),
+	}
+}
+
+// inc increments the counter of the given series, eg: `slaxy_webhook_rejected_total{reason="x"}`
+func (m *metrics) inc(series string) {
+	m.mu.Lock()
+	m.counters[series]++
+	m.mu.Unlock()
+}
+
+// get returns the current value of the given series
+func (m *metrics) get(series string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.counters[series]
+}
+
+// ServeHTTP writes all counters in the prometheus text format
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	series := make([]string, 0, len(m.counters))
+	for name := range m.counters {
+		series = append(series, name)
+	}
+	sort.Strings(series)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, name := range series {
+		fmt.Fprintf(w, "%s %d\n", name, m.counters[name])
+	}
+	m.mu.Unlock()
+}
@@ -0,0 +1,494 @@
+package slaxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Notifier posts alerts to a kind of destination.
+// Implement it and add it to Config.Notifiers to plug in your own destinations.
+type Notifier interface {
+	// Name is the destination kind the notifier is selected by, eg: "slack" for "slack:C0123456789"
+	Name() string
+	// Notify posts the event to its target
+	Notify(ctx context.Context, event *Event) error
+	// Check checks the configuration and connection of the notifier, it is only called once on start,
+	// /healthz doesn't call it
+	Check(ctx context.Context) error
+}
+
+// Event is an alert to be posted to one destination
+type Event struct {
+	// Target is the target of the destination, eg: the channel "C0123456789" of "slack:C0123456789"
+	Target string
+	// Hook is the normalized sentry webhook
+	Hook *Webhook
+
+	// route is the route which matched the destination, nil for default destinations
+	route *route
+}
+
+// noCheck is embedded by the notifiers whose destinations can't be checked without posting
+type noCheck struct{}
+
+// Check does nothing
+func (noCheck) Check(ctx context.Context) error {
+	return nil
+}
+
+// targetValidator is implemented by notifiers which can check on start whether a target exists
+type targetValidator interface {
+	validateTarget(target string) error
+}
+
+// newNotifiers returns the built-in notifiers and the ones of the config, the latter replace built-ins of the same name
+func newNotifiers(s *server, custom []Notifier) map[string]Notifier {
+	notifiers := map[string]Notifier{}
+	for _, n := range []Notifier{
+		&slackNotifier{s},
+		&discordNotifier{s},
+		&teamsNotifier{s},
+		&mattermostNotifier{s: s},
+		&rocketChatNotifier{s: s},
+		&telegramNotifier{s},
+		&pagerDutyNotifier{s: s},
+		&opsgenieNotifier{s: s},
+		&httpNotifier{s: s},
+		&emailNotifier{s: s},
+		&jiraNotifier{s: s},
+		&githubNotifier{s: s},
+	} {
+		notifiers[n.Name()] = n
+	}
+
+	for _, n := range custom {
+		notifiers[n.Name()] = n
+	}
+
+	return notifiers
+}
+
+// checkNotifiers checks all notifiers
+func (s *server) checkNotifiers(ctx context.Context) error {
+	for name, n := range s.notifiers {
+		if err := n.Check(ctx); err != nil {
+			return fmt.Errorf("%s notifier check failed, err: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// notify posts the hook to all destinations it is routed to, path is the destination of the webhook path
+func (s *server) notify(ctx context.Context, hook *Webhook, path destination) error {
+	destinations, routes := s.routeWithRoutes(hook, path)
+	if pageActionOf(hook) == pageResolve {
+		// resolve the pages of the issue even if the resolution isn't routed to them
+		for _, d := range s.pagedDestinations(hook) {
+			if !containsDestination(destinations, d) {
+				destinations = append(destinations, d)
+			}
+		}
+	}
+
+	sortTicketDestinations(destinations)
+
+	var errs []error
+	for _, d := range destinations {
+		// drop repeated alerts, they show up in the summary
+		if s.suppressor != nil && isSuppressible(d) {
+			if ok, reason := s.suppressor.allow(hook, d, routes[d]); !ok {
+				s.metrics.inc(fmt.Sprintf(`slaxy_webhook_suppressed_total{reason=%q}`, reason))
+				s.logger.Debugf("Suppressed %s webhook of %s for %s", reason, hook.issueKey(), d)
+				continue
+			}
+		}
+
+		if err := s.notifyDestination(ctx, hook, d, routes[d]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// notifyDestination sends the hook to one destination, r is the route which matched it if any
+func (s *server) notifyDestination(ctx context.Context, hook *Webhook, d destination, r *route) error {
+	n, ok := s.notifiers[d.kind]
+	if !ok {
+		return fmt.Errorf("unknown destination %s", d)
+	}
+
+	if err := n.Notify(ctx, &Event{Target: d.target, Hook: hook, route: r}); err != nil {
+		return fmt.Errorf("%s: %w", d, err)
+	}
+
+	return nil
+}
+
+// containsDestination checks whether the destination is one of destinations
+func containsDestination(destinations []destination, d destination) bool {
+	for _, dst := range destinations {
+		if dst == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateDestination checks whether the notifier and the target of the destination exist
+func (s *server) validateDestination(d destination) error {
+	n, ok := s.notifiers[d.kind]
+	if !ok {
+		return fmt.Errorf("unknown destination %s", d)
+	}
+
+	if v, ok := n.(targetValidator); ok && d.target != "" {
+		return v.validateTarget(d.target)
+	}
+
+	return nil
+}
+
+// lookupFold returns the value of the name in a map of the config, viper lowercases map keys
+// so they are compared case-insensitive
+func lookupFold[T any](m map[string]T, name string) (T, bool) {
+	for key, value := range m {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// parsePath returns the destination of the webhook path
+// /webhook/sentry/:SlackChannelID
+// /webhook/sentry/:Notifier/:Target, eg: /webhook/sentry/discord/ops
+func (s *server) parsePath(path string) destination {
+	parts := strings.Split(path, "/")
+	target := parts[len(parts)-1]
+	if len(parts) > 1 {
+		if _, ok := s.notifiers[parts[len(parts)-2]]; ok {
+			return destination{kind: parts[len(parts)-2], target: target}
+		}
+	}
+
+	return destination{kind: destinationSlack, target: target}
+}
+
+// slackNotifier posts attachments to slack channels
+type slackNotifier struct {
+	s *server
+}
+
+// Name returns "slack"
+func (n *slackNotifier) Name() string {
+	return destinationSlack
+}
+
+// Notify posts the event to the slack channel
+func (n *slackNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.slackHandleHook(ctx, event.Hook, event.Target, event.route)
+}
+
+// Check tests the slack authentication
+func (n *slackNotifier) Check(ctx context.Context) error {
+	if n.s.slack == nil {
+		return nil
+	}
+
+	_, err := n.s.slack.AuthTestContext(ctx)
+	if err != nil {
+		return fmt.Errorf("slack auth failed, err=%w", err)
+	}
+
+	return nil
+}
+
+// discordNotifier posts messages to discord webhooks
+type discordNotifier struct {
+	s *server
+}
+
+// Name returns "discord"
+func (n *discordNotifier) Name() string {
+	return destinationDiscord
+}
+
+// Notify posts the event to the named discord webhook
+func (n *discordNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.discordHandleHook(ctx, event.Hook, event.Target, event.route)
+}
+
+// Check checks the connection to all discord webhooks
+func (n *discordNotifier) Check(ctx context.Context) error {
+	if n.s.cfg.DiscordWebhookURL != "" {
+		if err := n.s.checkDiscordWebhook(ctx, n.s.cfg.DiscordWebhookURL); err != nil {
+			return err
+		}
+	}
+
+	for name, url := range n.s.cfg.DiscordWebhooks {
+		if err := n.s.checkDiscordWebhook(ctx, url); err != nil {
+			return fmt.Errorf("discord webhook %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateTarget checks whether the discord webhook exists
+func (n *discordNotifier) validateTarget(target string) error {
+	_, err := n.s.discordWebhookURL(target)
+	return err
+}
+
+// teamsNotifier posts adaptive cards to teams webhooks
+type teamsNotifier struct {
+	s *server
+}
+
+// Name returns "teams"
+func (n *teamsNotifier) Name() string {
+	return destinationTeams
+}
+
+// Notify posts the event to the named teams webhook
+func (n *teamsNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.teamsHandleHook(ctx, event.Hook, event.Target)
+}
+
+// Check checks the urls of all teams webhooks, they can't be requested without posting
+func (n *teamsNotifier) Check(ctx context.Context) error {
+	for name, url := range n.s.cfg.TeamsWebhooks {
+		if err := checkTeamsWebhook(url); err != nil {
+			return fmt.Errorf("teams webhook %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateTarget checks whether the teams webhook exists
+func (n *teamsNotifier) validateTarget(target string) error {
+	_, err := n.s.teamsWebhookURL(target)
+	return err
+}
+
+// mattermostNotifier posts attachments to mattermost webhooks
+type mattermostNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "mattermost"
+func (n *mattermostNotifier) Name() string {
+	return destinationMattermost
+}
+
+// Notify posts the event to the named mattermost webhook
+func (n *mattermostNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.mattermostHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the mattermost webhook exists
+func (n *mattermostNotifier) validateTarget(target string) error {
+	_, err := n.s.mattermostWebhookURL(target)
+	return err
+}
+
+// rocketChatNotifier posts attachments to rocket.chat webhooks
+type rocketChatNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "rocketchat"
+func (n *rocketChatNotifier) Name() string {
+	return destinationRocketChat
+}
+
+// Notify posts the event to the named rocket.chat webhook
+func (n *rocketChatNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.rocketChatHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the rocket.chat webhook exists
+func (n *rocketChatNotifier) validateTarget(target string) error {
+	_, err := n.s.rocketChatWebhookURL(target)
+	return err
+}
+
+// telegramNotifier sends messages to telegram chats
+type telegramNotifier struct {
+	s *server
+}
+
+// Name returns "telegram"
+func (n *telegramNotifier) Name() string {
+	return destinationTelegram
+}
+
+// Notify sends the event to the telegram chat
+func (n *telegramNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.telegramHandleHook(ctx, event.Hook, event.Target)
+}
+
+// Check tests the telegram bot token
+func (n *telegramNotifier) Check(ctx context.Context) error {
+	if n.s.cfg.TelegramToken == "" {
+		return nil
+	}
+
+	return n.s.telegramCall(ctx, "getMe", nil)
+}
+
+// validateTarget checks whether the telegram bot is configured
+func (n *telegramNotifier) validateTarget(target string) error {
+	if n.s.cfg.TelegramToken == "" {
+		return fmt.Errorf("no telegram token configured for chat %q", target)
+	}
+
+	return nil
+}
+
+// pagerDutyNotifier triggers and resolves pagerduty incidents
+type pagerDutyNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "pagerduty"
+func (n *pagerDutyNotifier) Name() string {
+	return destinationPagerDuty
+}
+
+// Notify triggers or resolves the incident of the event at the named pagerduty service
+func (n *pagerDutyNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.pagerDutyHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the pagerduty service exists
+func (n *pagerDutyNotifier) validateTarget(target string) error {
+	_, err := n.s.pagerDutyRoutingKey(target)
+	return err
+}
+
+// opsgenieNotifier creates and closes opsgenie alerts
+type opsgenieNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "opsgenie"
+func (n *opsgenieNotifier) Name() string {
+	return destinationOpsgenie
+}
+
+// Notify creates or closes the alert of the event at the named opsgenie integration
+func (n *opsgenieNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.opsgenieHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the opsgenie integration exists
+func (n *opsgenieNotifier) validateTarget(target string) error {
+	_, err := n.s.opsgenieAPIKey(target)
+	return err
+}
+
+// httpNotifier sends requests to http endpoints
+type httpNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "http"
+func (n *httpNotifier) Name() string {
+	return destinationHTTP
+}
+
+// Notify sends the event to the named http destination
+func (n *httpNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.httpHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the http destination exists
+func (n *httpNotifier) validateTarget(target string) error {
+	_, err := n.s.httpDestination(target)
+	return err
+}
+
+// emailNotifier sends alerts and digests by email
+type emailNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "email"
+func (n *emailNotifier) Name() string {
+	return destinationEmail
+}
+
+// Notify sends the event to the named email destination or adds it to its digest
+func (n *emailNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.emailHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the email destination exists and a mail server is configured
+func (n *emailNotifier) validateTarget(target string) error {
+	if n.s.cfg.SMTP.Addr == "" {
+		return fmt.Errorf("no smtp server configured for email destination %q", target)
+	}
+
+	_, err := n.s.emailDestination(target)
+	return err
+}
+
+// jiraNotifier opens jira issues
+type jiraNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "jira"
+func (n *jiraNotifier) Name() string {
+	return destinationJira
+}
+
+// Notify opens a jira issue in the named project unless the sentry issue has one already
+func (n *jiraNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.jiraHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the jira project exists
+func (n *jiraNotifier) validateTarget(target string) error {
+	_, err := n.s.jiraProject(target)
+	return err
+}
+
+// githubNotifier opens github issues
+type githubNotifier struct {
+	noCheck
+	s *server
+}
+
+// Name returns "github"
+func (n *githubNotifier) Name() string {
+	return destinationGitHub
+}
+
+// Notify opens a github issue in the named repository unless the sentry issue has one already
+func (n *githubNotifier) Notify(ctx context.Context, event *Event) error {
+	return n.s.githubHandleHook(ctx, event.Hook, event.Target)
+}
+
+// validateTarget checks whether the github repository exists
+func (n *githubNotifier) validateTarget(target string) error {
+	_, err := n.s.githubRepo(target)
+	return err
+}
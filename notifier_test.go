@@ -0,0 +1,88 @@
+package slaxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingNotifier records the events it is notified about
+type recordingNotifier struct {
+	name   string
+	events []*Event
+	err    error
+}
+
+func (n *recordingNotifier) Name() string {
+	return n.name
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event *Event) error {
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func (n *recordingNotifier) Check(ctx context.Context) error {
+	return nil
+}
+
+// setupTestServer sets the server up on a random port without serving, it is stopped with the test
+func setupTestServer(t *testing.T, s *server) error {
+	t.Cleanup(func() { _ = s.Stop() })
+
+	return s.setup("127.0.0.1:0", func(l net.Listener) { _ = l.Close() })
+}
+
+func TestCustomNotifier(t *testing.T) {
+	pager := &recordingNotifier{name: "pager"}
+	slack := &recordingNotifier{name: "slack"}
+	s := New(Config{
+		Notifiers: []Notifier{pager, slack},
+		Routes: []Route{
+			{Level: "fatal", Destinations: []string{"pager:oncall", "slack"}},
+		},
+	}, NewNullLogger()).(*server)
+	if err := setupTestServer(t, s); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"project_name": "backend", "level": "fatal", "event": {"title": "boom"}}`
+	rec := httptest.NewRecorder()
+	s.handleWebhook(rec, httptest.NewRequest(http.MethodPost, "/webhook/sentry/C0123", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+
+	if len(pager.events) != 1 || pager.events[0].Target != "oncall" || pager.events[0].Hook.Title() != "boom" {
+		t.Errorf("unexpected pager events %+v", pager.events)
+	}
+	if len(slack.events) != 1 || slack.events[0].Target != "C0123" {
+		t.Errorf("built-in slack notifier should be replaced, got %+v", slack.events)
+	}
+
+	// a custom notifier is selectable by path as well
+	pager.err = errors.New("pager down")
+	rec = httptest.NewRecorder()
+	body = `{"project_name": "backend", "level": "error", "event": {"title": "bang"}}`
+	s.handleWebhook(rec, httptest.NewRequest(http.MethodPost, "/webhook/sentry/pager/backend", strings.NewReader(body)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected the notifier error to fail the request, got %d", rec.Code)
+	}
+	if len(pager.events) != 2 || pager.events[1].Target != "backend" {
+		t.Errorf("unexpected pager events %+v", pager.events)
+	}
+}
+
+func TestUnknownRouteDestination(t *testing.T) {
+	s := New(Config{
+		Routes: []Route{{Destinations: []string{"pigeon:home"}}},
+	}, NewNullLogger()).(*server)
+
+	if err := setupTestServer(t, s); err == nil {
+		t.Error("expected an error for an unknown destination")
+	}
+}
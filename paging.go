@@ -0,0 +1,145 @@
+package slaxy
+
+import (
+	"strings"
+)
+
+// pageAction is what a paging destination does for a hook
+type pageAction int
+
+const (
+	pageSkip pageAction = iota
+	pageTrigger
+	pageResolve
+)
+
+// pageActionOf returns whether the hook triggers or resolves a page, other lifecycle changes and summaries are skipped
+func pageActionOf(hook *Webhook) pageAction {
+	switch {
+	case hook.Resource == resourceSummary:
+		return pageSkip
+	case hook.isResolution():
+		return pageResolve
+	case hook.isStatusChange() && !hook.isRegression():
+		return pageSkip
+	default:
+		return pageTrigger
+	}
+}
+
+// isPagingDestination checks whether the destination pages someone
+func isPagingDestination(d destination) bool {
+	return d.kind == destinationPagerDuty || d.kind == destinationOpsgenie
+}
+
+// pageSeverity returns the severity of the hook: "critical", "error", "warning" or "info"
+func pageSeverity(hook *Webhook) string {
+	if hook.MetricAlert != nil {
+		if hook.Action == metricAlertWarning {
+			return "warning"
+		}
+		return "critical"
+	}
+
+	switch strings.ToLower(hook.level()) {
+	case "fatal":
+		return "critical"
+	case "warning":
+		return "warning"
+	case "info", "debug":
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+// pageDetails returns the fields of the slack attachment as details of the page
+func (s *server) pageDetails(hook *Webhook) map[string]string {
+	attachment := s.createAttachment(hook)
+
+	details := make(map[string]string, len(attachment.Fields))
+	for _, field := range attachment.Fields {
+		if field.Value != "" {
+			details[field.Title] = field.Value
+		}
+	}
+
+	return details
+}
+
+// pageKey is the store key of the paging destinations which were triggered for an issue
+func pageKey(hook *Webhook) string {
+	return "paged:" + hook.issueKey()
+}
+
+// rememberPage remembers that the destination was paged for the issue of the hook, so it gets resolved
+// even if the resolution isn't routed there
+func (s *server) rememberPage(hook *Webhook, d destination) error {
+	if hook.issueKey() == "" {
+		return nil
+	}
+
+	paged := s.pagedDestinations(hook)
+	if containsDestination(paged, d) {
+		return nil
+	}
+
+	return s.savePages(hook, append(paged, d))
+}
+
+// forgetPage forgets that the destination was paged for the issue of the hook
+func (s *server) forgetPage(hook *Webhook, d destination) error {
+	if hook.issueKey() == "" {
+		return nil
+	}
+
+	var paged []destination
+	for _, p := range s.pagedDestinations(hook) {
+		if p != d {
+			paged = append(paged, p)
+		}
+	}
+
+	return s.savePages(hook, paged)
+}
+
+// savePages stores the paged destinations of the issue of the hook
+func (s *server) savePages(hook *Webhook, paged []destination) error {
+	if len(paged) == 0 {
+		return s.store.Delete(pageKey(hook))
+	}
+
+	names := make([]string, 0, len(paged))
+	for _, p := range paged {
+		names = append(names, p.String())
+	}
+
+	return s.store.Set(pageKey(hook), strings.Join(names, ","), s.cfg.StoreTTL)
+}
+
+// pagedDestinations returns the paging destinations which were triggered for the issue of the hook
+func (s *server) pagedDestinations(hook *Webhook) []destination {
+	if hook.issueKey() == "" {
+		return nil
+	}
+
+	value, ok, err := s.store.Get(pageKey(hook))
+	if err != nil {
+		s.logger.Warnf("failed to load paged destinations of %s: %s", hook.issueKey(), err)
+		return nil
+	}
+	if !ok || value == "" {
+		return nil
+	}
+
+	var destinations []destination
+	for _, name := range strings.Split(value, ",") {
+		d, err := parseDestination(name)
+		if err != nil {
+			continue
+		}
+		destinations = append(destinations, d)
+	}
+
+	return destinations
+}
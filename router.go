@@ -0,0 +1,365 @@
+package slaxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// destination kinds
+const (
+	destinationSlack      = "slack"
+	destinationDiscord    = "discord"
+	destinationTeams      = "teams"
+	destinationMattermost = "mattermost"
+	destinationRocketChat = "rocketchat"
+	destinationTelegram   = "telegram"
+	destinationPagerDuty  = "pagerduty"
+	destinationOpsgenie   = "opsgenie"
+	destinationHTTP       = "http"
+	destinationEmail      = "email"
+	destinationJira       = "jira"
+	destinationGitHub     = "github"
+)
+
+// Route sends alerts matching all of its patterns to its destinations.
+// Patterns are globs like "backend-*" or regular expressions enclosed in slashes like "/^(error|fatal)$/",
+// empty patterns match everything.
+type Route struct {
+	Name        string            `mapstructure:"name"`
+	Project     string            `mapstructure:"project"` // the project slug
+	Environment string            `mapstructure:"environment"`
+	Level       string            `mapstructure:"level"`
+	Release     string            `mapstructure:"release"`
+	Platform    string            `mapstructure:"platform"`
+	Rule        string            `mapstructure:"rule"` // any of the triggering rules
+	Tags        map[string]string `mapstructure:"tags"`
+
+	// Destinations like "slack:C0123456789" or "discord:ops", the kind alone is the destination of the webhook path
+	// or for discord the default webhook
+	Destinations []string `mapstructure:"destinations"`
+	// Continue evaluates the following routes as well after this one matched
+	Continue bool `mapstructure:"continue"`
+
+	// Template overrides the message layout of the slack and discord destinations
+	Template *MessageTemplate `mapstructure:"template"`
+	// SlackFormat posts legacy "attachments" (the default) or block kit "blocks" to the slack destinations
+	SlackFormat string `mapstructure:"slack-format"`
+	// LevelStyles override the global level styles for the slack and discord destinations
+	LevelStyles map[string]LevelStyle `mapstructure:"level-styles"`
+}
+
+// destination is where an alert is posted to, eg: the slack channel "C0123456789"
+type destination struct {
+	kind   string
+	target string
+}
+
+// String returns the destination as configured
+func (d destination) String() string {
+	if d.target == "" {
+		return d.kind
+	}
+
+	return d.kind + ":" + d.target
+}
+
+// fallbackDestinations are used when neither a route matches nor default destinations are configured
+var fallbackDestinations = []destination{{kind: destinationSlack}, {kind: destinationDiscord}}
+
+// route is a compiled Route
+type route struct {
+	name         string
+	matchers     []matcher
+	destinations []destination
+	cont         bool
+	template     *messageTemplate
+	slackBlocks  bool
+	levelStyles  map[string]LevelStyle
+}
+
+// matcher matches one field of a hook
+type matcher struct {
+	field   string
+	pattern *regexp.Regexp
+	values  func(hook *Webhook) []string
+}
+
+// compileRoutes compiles all routes
+func compileRoutes(routes []Route) ([]*route, error) {
+	compiled := make([]*route, 0, len(routes))
+	for i := range routes {
+		r, err := compileRoute(&routes[i])
+		if err != nil {
+			name := routes[i].Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("invalid route %s, err: %w", name, err)
+		}
+		compiled = append(compiled, r)
+	}
+
+	return compiled, nil
+}
+
+// compileRoute compiles the patterns and destinations of a route
+func compileRoute(r *Route) (*route, error) {
+	if len(r.Destinations) == 0 {
+		return nil, fmt.Errorf("no destinations")
+	}
+
+	destinations, err := parseDestinations(r.Destinations)
+	if err != nil {
+		return nil, err
+	}
+	compiled := &route{name: r.Name, destinations: destinations, cont: r.Continue, levelStyles: r.LevelStyles}
+
+	compiled.template, err = compileMessageTemplate(r.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateLevelStyles(r.LevelStyles); err != nil {
+		return nil, err
+	}
+
+	switch r.SlackFormat {
+	case "", slackFormatAttachments:
+	case slackFormatBlocks:
+		compiled.slackBlocks = true
+	default:
+		return nil, fmt.Errorf("invalid slack format %q", r.SlackFormat)
+	}
+
+	fields := []struct {
+		field   string
+		pattern string
+		values  func(hook *Webhook) []string
+	}{
+		{"project", r.Project, func(hook *Webhook) []string { return []string{hook.ProjectSlug} }},
+		{"environment", r.Environment, func(hook *Webhook) []string { return []string{hook.environment()} }},
+		{"level", r.Level, func(hook *Webhook) []string { return []string{hook.level()} }},
+		{"release", r.Release, func(hook *Webhook) []string { return []string{hook.release()} }},
+		{"platform", r.Platform, func(hook *Webhook) []string { return []string{hook.Event.Platform} }},
+		{"rule", r.Rule, func(hook *Webhook) []string { return hook.TriggeringRules }},
+	}
+	for _, f := range fields {
+		if f.pattern == "" {
+			continue
+		}
+
+		pattern, err := compilePattern(f.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern, err: %w", f.field, err)
+		}
+		compiled.matchers = append(compiled.matchers, matcher{field: f.field, pattern: pattern, values: f.values})
+	}
+
+	for key, value := range r.Tags {
+		pattern, err := compilePattern(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of tag %s, err: %w", key, err)
+		}
+
+		key := key
+		compiled.matchers = append(compiled.matchers, matcher{
+			field:   "tag " + key,
+			pattern: pattern,
+			values:  func(hook *Webhook) []string { return hook.tagValues(key) },
+		})
+	}
+
+	return compiled, nil
+}
+
+// parseDestinations parses all destinations
+func parseDestinations(dsts []string) ([]destination, error) {
+	destinations := make([]destination, 0, len(dsts))
+	for _, dst := range dsts {
+		d, err := parseDestination(dst)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, d)
+	}
+
+	return destinations, nil
+}
+
+// parseDestination parses a destination like "slack:C0123456789"
+func parseDestination(dst string) (destination, error) {
+	kind, target, _ := strings.Cut(strings.TrimSpace(dst), ":")
+	if kind == "" {
+		return destination{}, fmt.Errorf("invalid destination %q", dst)
+	}
+
+	return destination{kind: kind, target: target}, nil
+}
+
+// compilePattern compiles a glob or a regular expression enclosed in slashes
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return regexp.Compile(pattern[1 : len(pattern)-1])
+	}
+
+	glob := regexp.QuoteMeta(pattern)
+	glob = strings.ReplaceAll(glob, `\*`, ".*")
+	glob = strings.ReplaceAll(glob, `\?`, ".")
+
+	return regexp.Compile("^" + glob + "$")
+}
+
+// matches checks whether all matchers of the route match the hook
+func (r *route) matches(hook *Webhook) bool {
+	for _, m := range r.matchers {
+		if !m.matches(hook) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matches checks whether any value of the field matches
+func (m *matcher) matches(hook *Webhook) bool {
+	for _, value := range m.values(hook) {
+		if m.pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// route returns the destinations of the hook, path is the destination of the webhook path
+func (s *server) route(hook *Webhook, path destination) []destination {
+	destinations, _ := s.routeWithRoutes(hook, path)
+	return destinations
+}
+
+// routeWithRoutes returns the destinations of the hook and the route which matched each of them first,
+// code owner, default and fallback destinations have no route
+func (s *server) routeWithRoutes(hook *Webhook, path destination) ([]destination, map[destination]*route) {
+	var destinations []destination
+	var routes []*route
+	for _, r := range s.routes {
+		if !r.matches(hook) {
+			continue
+		}
+
+		s.logger.Debugf("route %s matched %s", r.name, hook.issueKey())
+		for _, d := range r.destinations {
+			destinations = append(destinations, d)
+			routes = append(routes, r)
+		}
+		if !r.cont {
+			break
+		}
+	}
+
+	if len(destinations) == 0 {
+		destinations = s.defaultDestinations
+	}
+	if len(destinations) == 0 {
+		destinations = fallbackDestinations
+		// a webhook path of another notifier only posts to its target
+		if path.kind != destinationSlack {
+			destinations = []destination{path}
+		}
+	}
+
+	// the owners of the code get the alert as well
+	if owners := s.codeOwnerDestinations(hook); len(owners) > 0 {
+		destinations = append(append([]destination{}, destinations...), owners...)
+	}
+
+	// resolve the destination of the webhook path and remove duplicates
+	routeOf := make(map[destination]*route, len(destinations))
+	seen := make(map[destination]bool, len(destinations))
+	resolved := make([]destination, 0, len(destinations))
+	for i, d := range destinations {
+		if d.target == "" && d.kind == path.kind {
+			d.target = path.target
+		}
+		// only discord has a default webhook
+		if d.target == "" && requiresTarget(d.kind) {
+			s.logger.Warnf("Dropped destination %s of %s, the webhook path %s has no %s target", d, hook.issueKey(), path, d.kind)
+			continue
+		}
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		resolved = append(resolved, d)
+		if i < len(routes) {
+			routeOf[d] = routes[i]
+		}
+	}
+
+	return resolved, routeOf
+}
+
+// requiresTarget reports whether the built-in destination kind has no default target
+func requiresTarget(kind string) bool {
+	switch kind {
+	case destinationSlack, destinationTeams, destinationMattermost, destinationRocketChat, destinationTelegram,
+		destinationPagerDuty, destinationOpsgenie, destinationHTTP, destinationEmail,
+		destinationJira, destinationGitHub:
+		return true
+	default:
+		return false
+	}
+}
+
+// environment returns the environment of the event
+func (w *Webhook) environment() string {
+	if w.Event.Environment != "" {
+		return w.Event.Environment
+	}
+
+	return w.tagValue("environment")
+}
+
+// level returns the level of the hook or its event
+func (w *Webhook) level() string {
+	if w.Level != "" {
+		return w.Level
+	}
+	if w.Event.Level != "" {
+		return w.Event.Level
+	}
+
+	return w.tagValue("level")
+}
+
+// release returns the release of the event
+func (w *Webhook) release() string {
+	if w.Event.Release != "" {
+		return w.Event.Release
+	}
+
+	return w.tagValue("sentry:release")
+}
+
+// tagValue returns the first value of the event tag
+func (w *Webhook) tagValue(key string) string {
+	values := w.tagValues(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// tagValues returns all values of the event tag, the key is case-insensitive
+func (w *Webhook) tagValues(key string) []string {
+	var values []string
+	for _, tag := range w.Event.Tags {
+		if strings.EqualFold(tag[0], key) {
+			values = append(values, tag[1])
+		}
+	}
+
+	return values
+}
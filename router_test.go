@@ -0,0 +1,140 @@
+package slaxy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	routes, err := compileRoutes([]Route{
+		{
+			Name:         "fatal",
+			Level:        "/^(fatal|critical)$/",
+			Destinations: []string{"slack:oncall"},
+			Continue:     true,
+		},
+		{
+			Name:         "backend production",
+			Project:      "backend-*",
+			Environment:  "prod*",
+			Tags:         map[string]string{"server_name": "web-?"},
+			Destinations: []string{"slack:backend", "discord"},
+		},
+		{
+			Name:         "releases",
+			Release:      "v2.*",
+			Rule:         "*regression*",
+			Destinations: []string{"slack:releases"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults, err := parseDestinations([]string{"slack"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{}, NewNullLogger()).(*server)
+	s.routes = routes
+	s.defaultDestinations = defaults
+
+	tests := []struct {
+		name string
+		hook *Webhook
+		want []destination
+	}{
+		{
+			name: "first match stops",
+			hook: &Webhook{ProjectSlug: "backend-api", Event: SentryEvent{
+				Environment: "production",
+				Release:     "v2.0.0",
+				Tags:        []SentryTag{{"server_name", "web-1"}},
+			}, TriggeringRules: []string{"Notify on regression"}},
+			want: []destination{{kind: "slack", target: "backend"}, {kind: "discord"}},
+		},
+		{
+			name: "continue",
+			hook: &Webhook{ProjectSlug: "backend-api", Level: "fatal", Event: SentryEvent{
+				Environment: "production",
+				Tags:        []SentryTag{{"server_name", "web-1"}},
+			}},
+			want: []destination{{kind: "slack", target: "oncall"}, {kind: "slack", target: "backend"}, {kind: "discord"}},
+		},
+		{
+			name: "tag mismatch",
+			hook: &Webhook{ProjectSlug: "backend-api", Event: SentryEvent{
+				Environment: "production",
+				Tags:        []SentryTag{{"server_name", "worker-1"}},
+			}},
+			want: []destination{{kind: "slack", target: "alerts"}},
+		},
+		{
+			name: "environment from tags",
+			hook: &Webhook{ProjectSlug: "backend-api", Event: SentryEvent{
+				Tags: []SentryTag{{"environment", "prod"}, {"server_name", "web-2"}},
+			}},
+			want: []destination{{kind: "slack", target: "backend"}, {kind: "discord"}},
+		},
+		{
+			name: "rule",
+			hook: &Webhook{Event: SentryEvent{Release: "v2.1.0"}, TriggeringRules: []string{"new issue", "regression in release"}},
+			want: []destination{{kind: "slack", target: "releases"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.route(tt.hook, destination{kind: destinationSlack, target: "alerts"}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRouteFallback(t *testing.T) {
+	s := New(Config{}, NewNullLogger()).(*server)
+
+	want := []destination{{kind: "slack", target: "alerts"}, {kind: "discord"}}
+	if got := s.route(&Webhook{}, s.parsePath("/webhook/sentry/alerts")); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	want = []destination{{kind: "discord", target: "ops"}}
+	if got := s.route(&Webhook{}, s.parsePath("/webhook/sentry/discord/ops")); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCompileRoutesErrors(t *testing.T) {
+	for name, r := range map[string]Route{
+		"no destinations":   {Project: "backend"},
+		"empty destination": {Destinations: []string{":home"}},
+		"invalid regex":     {Level: "/(/", Destinations: []string{"slack"}},
+	} {
+		if _, err := compileRoutes([]Route{r}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestValidateDestination(t *testing.T) {
+	s := New(Config{DiscordWebhooks: map[string]string{"ops": "https://discord.invalid/ops"}}, NewNullLogger()).(*server)
+
+	for dst, valid := range map[string]bool{
+		"slack:C0123":   true,
+		"discord":       true,
+		"discord:ops":   true,
+		"discord:other": false,
+		"teams:oncall":  false,
+		"pigeon:home":   false,
+	} {
+		d, err := parseDestination(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.validateDestination(d); (err == nil) != valid {
+			t.Errorf("%s: expected valid=%v, got err=%v", dst, valid, err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -22,6 +23,94 @@ type Config struct {
 	SlackToken        string        `mapstructure:"token"`
 	DiscordWebhookURL string        `mapstructure:"discord-webhook-url"`
 	ExcludedFields    []string      `mapstructure:"excluded-fields"`
+
+	// DiscordWebhooks are named discord webhook urls, selected by "discord:<name>" destinations
+	// or the webhook path /webhook/sentry/discord/<name>
+	DiscordWebhooks map[string]string `mapstructure:"discord-webhooks"`
+	// TeamsWebhooks are named teams incoming webhook or workflow urls, selected by "teams:<name>" destinations
+	// or the webhook path /webhook/sentry/teams/<name>
+	TeamsWebhooks map[string]string `mapstructure:"teams-webhooks"`
+	// MattermostWebhooks are named mattermost incoming webhook urls, selected by "mattermost:<name>" destinations
+	// or the webhook path /webhook/sentry/mattermost/<name>
+	MattermostWebhooks map[string]string `mapstructure:"mattermost-webhooks"`
+	// RocketChatWebhooks are named rocket.chat incoming webhook urls, selected by "rocketchat:<name>" destinations
+	// or the webhook path /webhook/sentry/rocketchat/<name>
+	RocketChatWebhooks map[string]string `mapstructure:"rocketchat-webhooks"`
+
+	// TelegramToken is the token of the telegram bot, alerts are posted to "telegram:<chat>" destinations
+	// or the webhook path /webhook/sentry/telegram/<chat>
+	TelegramToken string `mapstructure:"telegram-token"`
+	// TelegramChats are named chat ids, other targets are used as chat id or @channelusername
+	TelegramChats map[string]string `mapstructure:"telegram-chats"`
+	// TelegramAPIURL is the url of the telegram bot api, defaults to https://api.telegram.org
+	TelegramAPIURL string `mapstructure:"telegram-api-url"`
+
+	// PagerDutyRoutingKeys are integration keys of named pagerduty services, paged by "pagerduty:<name>" destinations
+	PagerDutyRoutingKeys map[string]string `mapstructure:"pagerduty-routing-keys"`
+	// PagerDutyURL is the url of the pagerduty events api v2, defaults to https://events.pagerduty.com/v2/enqueue
+	PagerDutyURL string `mapstructure:"pagerduty-url"`
+	// OpsgenieAPIKeys are api keys of named opsgenie integrations, paged by "opsgenie:<name>" destinations
+	OpsgenieAPIKeys map[string]string `mapstructure:"opsgenie-api-keys"`
+	// OpsgenieAPIURL is the url of the opsgenie api, defaults to https://api.opsgenie.com
+	OpsgenieAPIURL string `mapstructure:"opsgenie-api-url"`
+
+	// HTTPDestinations are named http endpoints, selected by "http:<name>" destinations
+	// or the webhook path /webhook/sentry/http/<name>
+	HTTPDestinations map[string]HTTPDestination `mapstructure:"http-destinations"`
+
+	// SMTP is the mail server of the email destinations
+	SMTP SMTPConfig `mapstructure:"smtp"`
+	// EmailDestinations are named recipients, selected by "email:<name>" destinations
+	// or the webhook path /webhook/sentry/email/<name>, "email:<address>" sends to the address
+	EmailDestinations map[string]EmailDestination `mapstructure:"email-destinations"`
+
+	// JiraProjects open jira issues for alerts routed to "jira:<name>" destinations
+	JiraProjects map[string]JiraProject `mapstructure:"jira-projects"`
+	// GitHubRepos open github issues for alerts routed to "github:<name>" destinations
+	GitHubRepos map[string]GitHubRepo `mapstructure:"github-repos"`
+
+	// ClientSecret is the sentry integration client secret used to verify the Sentry-Hook-Signature header
+	ClientSecret string `mapstructure:"client-secret"`
+	// ClientSecrets overrides the client secret per webhook path, keyed by its destination like "discord:ops",
+	// the secrets of slack channels by their bare id like "C0123456789" as well
+	ClientSecrets map[string]string `mapstructure:"client-secrets"`
+
+	// SlackThreads posts follow-up events of an issue as thread replies to its first message
+	SlackThreads bool `mapstructure:"slack-threads"`
+	// SlackThreadBroadcast also sends the thread replies to the channel
+	SlackThreadBroadcast bool `mapstructure:"slack-thread-broadcast"`
+	// SlackUpdateMessages updates the first message of an issue when it gets resolved or regresses
+	SlackUpdateMessages bool `mapstructure:"slack-update-messages"`
+
+	// StorePath is the path of the on-disk store, the mappings are kept in memory if empty
+	StorePath string `mapstructure:"store-path"`
+	// StoreTTL is how long the mappings are kept, forever if zero
+	StoreTTL time.Duration `mapstructure:"store-ttl"`
+	// StoreCompactInterval is how often expired mappings are removed
+	StoreCompactInterval time.Duration `mapstructure:"store-compact-interval"`
+	// Store keeps the issue to message mappings, it takes precedence over StorePath
+	Store Store `mapstructure:"-"`
+
+	// Suppression deduplicates and rate-limits repeated alerts
+	Suppression SuppressionConfig `mapstructure:"suppression"`
+
+	// Stacktrace controls how many frames and source lines of the stacktraces are rendered
+	Stacktrace StacktraceConfig `mapstructure:"stacktrace"`
+	// LevelStyles are the colors, emoji and mentions of new alerts by level, eg: "fatal"
+	LevelStyles map[string]LevelStyle `mapstructure:"level-styles"`
+	// Mentions maps the sentry users and teams of alerts onto slack and discord mentions
+	Mentions MentionConfig `mapstructure:"mentions"`
+
+	// Routes choose the destinations of an alert by its content
+	Routes []Route `mapstructure:"routes"`
+	// DefaultDestinations are used if no route matches, defaults to the slack channel of the webhook path and discord
+	DefaultDestinations []string `mapstructure:"default-destinations"`
+	// CodeOwners sends alerts to the owners of the code in their stacktrace as well and mentions them
+	CodeOwners CodeOwnersConfig `mapstructure:"code-owners"`
+
+	// Notifiers are additional destinations selected by "<name>:<target>",
+	// they replace the built-in notifiers of the same name
+	Notifiers []Notifier `mapstructure:"-"`
 }
 
 // server types
@@ -34,6 +123,19 @@ type server struct {
 	slack          *slack.Client
 	client         *resty.Client
 	excludedFields []*regexp.Regexp
+	metrics        *metrics
+	store          Store // nil until setup opens the on-disk store if StorePath is set
+	suppressor     *suppressor
+	stopOnce       sync.Once
+	digests        sync.WaitGroup
+
+	routes              []*route
+	defaultDestinations []destination
+	notifiers           map[string]Notifier
+	httpDestinations    map[string]*httpDestination
+	emailDestinations   map[string]*emailDestination
+	codeOwners          *codeOwners
+	ticketLocks         keyedMutex
 }
 
 // Server represents a server instance
@@ -43,14 +145,26 @@ type Server interface {
 	Errors() <-chan error
 }
 
-// New creates a new server instance
+// New creates a new server instance, the on-disk store of StorePath is opened by Start
 func New(cfg Config, logger Logger) Server {
-	return &server{
+	// the on-disk store is opened on start
+	store := cfg.Store
+	if store == nil && cfg.StorePath == "" {
+		store = NewMemoryStore()
+	}
+
+	s := &server{
 		cfg:     cfg,
 		logger:  logger,
 		done:    make(chan struct{}, 1),
 		errChan: make(chan error, 100),
+		metrics: newMetrics(),
+		store:   store,
+		client:  resty.New(),
 	}
+	s.notifiers = newNotifiers(s, cfg.Notifiers)
+
+	return s
 }
 
 // Start starts up the server
@@ -58,17 +172,49 @@ func (s *server) Start() error {
 	return s.setup(s.cfg.Addr, s.handleWeb)
 }
 
-// Stop gracefully shuts down the server
+// Stop gracefully shuts down the server, calling it again does nothing
 func (s *server) Stop() error {
-	s.done <- struct{}{}
+	var err error
+	s.stopOnce.Do(func() {
+		close(s.done)
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.GracePeriod)
+		defer cancel()
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.GracePeriod)
-	err := s.srv.Shutdown(ctx)
-	cancel()
+		if s.srv != nil {
+			err = s.srv.Shutdown(ctx)
+		}
+		err = errors.Join(err, s.waitDigests(ctx))
+		if s.store != nil {
+			err = errors.Join(err, s.store.Close())
+		}
+	})
 
 	return err
 }
 
+// waitDigests waits until the pending email digests are sent or the grace period is over,
+// sending them is still bounded by the smtp timeout without a grace period
+func (s *server) waitDigests(ctx context.Context) error {
+	if s.cfg.GracePeriod <= 0 {
+		s.digests.Wait()
+		return nil
+	}
+
+	sent := make(chan struct{})
+	go func() {
+		s.digests.Wait()
+		close(sent)
+	}()
+
+	select {
+	case <-sent:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pending email digests were not sent within the grace period")
+	}
+}
+
 // Errors returns the error channel
 func (s *server) Errors() <-chan error {
 	return s.errChan
@@ -83,38 +229,128 @@ func (s *server) setup(addr string, handler handler) error {
 	}
 	s.excludedFields = excludedFields
 
-	if s.cfg.SlackToken != "" {
-		client := slack.New(s.cfg.SlackToken)
-		_, err := client.AuthTest()
-		if err != nil {
-			return fmt.Errorf("slack auth failed, err=%w", err)
-		}
-		s.slack = client
+	if err := validateLevelStyles(s.cfg.LevelStyles); err != nil {
+		return fmt.Errorf("invalid level styles, err: %w", err)
+	}
+
+	routes, err := compileRoutes(s.cfg.Routes)
+	if err != nil {
+		return err
 	}
+	s.routes = routes
 
-	if s.cfg.DiscordWebhookURL != "" {
+	s.defaultDestinations, err = parseDestinations(s.cfg.DefaultDestinations)
+	if err != nil {
+		return fmt.Errorf("invalid default destinations, err: %w", err)
+	}
+
+	s.suppressor, err = newSuppressor(s.cfg.Suppression)
+	if err != nil {
+		return err
+	}
+
+	s.httpDestinations, err = compileHTTPDestinations(s.cfg.HTTPDestinations)
+	if err != nil {
+		return err
+	}
+
+	s.emailDestinations, err = compileEmailDestinations(s.cfg.EmailDestinations)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.SlackToken != "" && s.slack == nil {
+		s.slack = slack.New(s.cfg.SlackToken)
+	}
+	if s.client == nil {
 		s.client = resty.New()
-		res, err := s.client.R().Get(s.cfg.DiscordWebhookURL)
-		if err != nil {
-			return fmt.Errorf("failed to check webhook connection err: %w", err)
+	}
+	if s.notifiers == nil {
+		s.notifiers = newNotifiers(s, s.cfg.Notifiers)
+	}
+
+	if err := s.checkNotifiers(context.Background()); err != nil {
+		return err
+	}
+
+	// all named destinations have to exist
+	for _, r := range s.routes {
+		for _, d := range r.destinations {
+			if err := s.validateDestination(d); err != nil {
+				return fmt.Errorf("invalid route %s, err: %w", r.name, err)
+			}
 		}
-		if res.StatusCode() >= 300 {
-			return fmt.Errorf("failed to get webhook info: %s", res.Body())
+	}
+	for _, d := range s.defaultDestinations {
+		if err := s.validateDestination(d); err != nil {
+			return fmt.Errorf("invalid default destinations, err: %w", err)
 		}
 	}
 
-	// start tcp listener
+	s.codeOwners, err = newCodeOwners(s.cfg.CodeOwners, s.validateDestination)
+	if err != nil {
+		return err
+	}
+
+	// listen before anything is started which would leak if the address is taken
 	l, err := net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s, err: %w", addr, err)
 	}
 
+	if s.store == nil && s.cfg.StorePath != "" {
+		store, err := NewBoltStore(s.cfg.StorePath)
+		if err != nil {
+			_ = l.Close()
+			return err
+		}
+		s.store = store
+	}
+	if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	go s.compactStore()
+	if s.suppressor != nil {
+		go s.postSummaries()
+	}
+	for _, d := range s.emailDestinations {
+		if d.interval > 0 {
+			s.digests.Add(1)
+			go s.sendDigests(d)
+		}
+	}
+	if s.codeOwners != nil {
+		go s.reloadCodeOwners()
+	}
+
 	s.logger.Info(fmt.Sprintf("Listening on %s", addr))
 	go s.handleListener(l, addr, handler)
 
 	return nil
 }
 
+// compactStore periodically removes expired mappings until the server is stopped
+func (s *server) compactStore() {
+	interval := s.cfg.StoreCompactInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.store.Compact(); err != nil {
+				s.errChan <- fmt.Errorf("failed to compact store, err: %w", err)
+			}
+		}
+	}
+}
+
 // handleListener handles a listener using the specified handler function
 func (s *server) handleListener(l net.Listener, addr string, handler handler) {
 	defer s.logger.Info(fmt.Sprintf("Listener %s shutdown", addr))
@@ -130,6 +366,7 @@ func (s *server) handleWeb(l net.Listener) {
 		w.Write([]byte("ok"))
 	})
 
+	mux.Handle("/metrics", s.metrics)
 	mux.HandleFunc("/webhook/sentry/", s.handleWebhook)
 
 	s.srv = &http.Server{
@@ -0,0 +1,47 @@
+package slaxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// signatureHeader is the header sentry uses to sign integration webhooks
+const signatureHeader = "Sentry-Hook-Signature"
+
+// secretFor returns the client secret used to verify requests for the destination of the webhook path.
+// Route specific secrets are keyed by the destination like "discord:ops", slack channels by their bare id as well,
+// so the routes of different notifiers never share a secret. They take precedence over the global one,
+// an empty result disables verification.
+func (s *server) secretFor(path destination) string {
+	keys := []string{path.String()}
+	if path.kind == destinationSlack {
+		keys = append(keys, path.target)
+	}
+
+	for _, key := range keys {
+		if secret, ok := lookupFold(s.cfg.ClientSecrets, key); ok {
+			return secret
+		}
+	}
+
+	return s.cfg.ClientSecret
+}
+
+// verifySignature checks the HMAC-SHA256 signature sentry computed over the raw body
+func verifySignature(secret string, body []byte, signature string) bool {
+	if signature == "" {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,77 @@
+package slaxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := `{"action":"triggered"}`
+
+	if !verifySignature("secret", []byte(body), sign("secret", body)) {
+		t.Error("valid signature was rejected")
+	}
+	if verifySignature("secret", []byte(body), sign("other", body)) {
+		t.Error("signature of another secret was accepted")
+	}
+	if verifySignature("secret", []byte(body), "") {
+		t.Error("empty signature was accepted")
+	}
+	if verifySignature("secret", []byte(body), "not-hex") {
+		t.Error("malformed signature was accepted")
+	}
+}
+
+func TestSecretFor(t *testing.T) {
+	s := New(Config{
+		ClientSecret:  "global",
+		ClientSecrets: map[string]string{"c0123": "route", "discord:ops": "discord", "slack:c0456": "channel"},
+	}, NewNullLogger()).(*server)
+
+	if got := s.secretFor(destination{kind: destinationSlack, target: "C0123"}); got != "route" {
+		t.Errorf("expected route secret, got %q", got)
+	}
+	if got := s.secretFor(destination{kind: destinationSlack, target: "C0456"}); got != "channel" {
+		t.Errorf("expected channel secret, got %q", got)
+	}
+	if got := s.secretFor(destination{kind: destinationDiscord, target: "ops"}); got != "discord" {
+		t.Errorf("expected discord secret, got %q", got)
+	}
+	// a slack channel named like a discord webhook doesn't share its secret
+	if got := s.secretFor(destination{kind: destinationSlack, target: "ops"}); got != "global" {
+		t.Errorf("expected global secret, got %q", got)
+	}
+	if got := s.secretFor(destination{kind: destinationDiscord, target: "C0123"}); got != "global" {
+		t.Errorf("expected global secret, got %q", got)
+	}
+	if got := s.secretFor(destination{kind: destinationSlack, target: "C9999"}); got != "global" {
+		t.Errorf("expected global secret, got %q", got)
+	}
+}
+
+func TestHandleWebhookRejectsInvalidSignature(t *testing.T) {
+	s := New(Config{ClientSecret: "secret"}, NewNullLogger()).(*server)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/sentry/C0123", strings.NewReader(`{}`))
+	req.Header.Set(signatureHeader, sign("wrong", `{}`))
+	rec := httptest.NewRecorder()
+	s.handleWebhook(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+	if got := s.metrics.get(`slaxy_webhook_rejected_total{reason="invalid_signature"}`); got != 1 {
+		t.Errorf("expected one rejected request, got %d", got)
+	}
+}
@@ -0,0 +1,206 @@
+package slaxy
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// StacktraceConfig controls how much of the stacktraces is rendered in messages
+type StacktraceConfig struct {
+	// Frames is the number of innermost frames rendered of each exception, 1 if empty
+	Frames int `mapstructure:"frames"`
+	// InAppOnly skips the frames of libraries, all frames are used if none is in-app
+	InAppOnly bool `mapstructure:"in-app-only"`
+	// ContextLines is the number of source lines rendered before and after the line of each frame
+	ContextLines int `mapstructure:"context-lines"`
+}
+
+// stacktraceFormat is the markup of a stacktrace
+type stacktraceFormat struct {
+	code  func(text string) string
+	block func(text string) string
+}
+
+// markdownStacktrace is the markup of slack, discord and most other chats
+var markdownStacktrace = stacktraceFormat{
+	code:  func(text string) string { return "`" + text + "`" },
+	block: func(text string) string { return "```\n" + text + "\n```" },
+}
+
+// jiraStacktrace is the markup of jira issues
+var jiraStacktrace = stacktraceFormat{
+	code:  func(text string) string { return "{{" + text + "}}" },
+	block: func(text string) string { return "{noformat}\n" + text + "\n{noformat}" },
+}
+
+// stacktraceOmitted is the space reserved for the note of the omitted frames
+const stacktraceOmitted = 32
+
+// stacktrace lengths of the destinations without a limit of their own that low
+const (
+	// slackStacktraceMax fits a block kit section with the field title
+	slackStacktraceMax = 2900
+	// telegramStacktraceMax spans a few messages
+	telegramStacktraceMax = 3 * telegramMaxLength
+	// ticketStacktraceMax keeps the descriptions of tickets readable
+	ticketStacktraceMax = 10000
+)
+
+// frames returns the innermost frames to render first and the number of left out ones
+func (c StacktraceConfig) frames(frames []StacktraceFrame) ([]StacktraceFrame, int) {
+	if c.InAppOnly {
+		var inApp []StacktraceFrame
+		for _, frame := range frames {
+			if frame.InApp {
+				inApp = append(inApp, frame)
+			}
+		}
+		if len(inApp) > 0 {
+			frames = inApp
+		}
+	}
+
+	n := c.Frames
+	if n <= 0 {
+		n = 1
+	}
+	if n > len(frames) {
+		n = len(frames)
+	}
+
+	// sentry sends the innermost frame last
+	selected := make([]StacktraceFrame, 0, n)
+	for i := len(frames) - 1; i >= len(frames)-n; i-- {
+		selected = append(selected, frames[i])
+	}
+
+	return selected, len(frames) - n
+}
+
+// renderStacktrace renders the stacktraces of the chained exceptions of the hook in at most max characters,
+// the latest exception comes first, frames which don't fit are left out, it is empty without a stacktrace
+func (s *server) renderStacktrace(hook *Webhook, format stacktraceFormat, max int) string {
+	values := hook.Event.Exception.Values
+
+	var parts []string
+	length, omitted, full := 0, 0, false
+	add := func(part string) bool {
+		n := utf8.RuneCountInString(part) + 1
+		if full || length+n > max-stacktraceOmitted {
+			full = true
+			return false
+		}
+		parts = append(parts, part)
+		length += n
+
+		return true
+	}
+
+	rendered := 0
+	for i := len(values) - 1; i >= 0; i-- {
+		frames, skipped := s.cfg.Stacktrace.frames(values[i].Stacktrace.Frames)
+		if len(frames) == 0 {
+			continue
+		}
+		omitted += skipped
+
+		title := exceptionTitle(values[i])
+		if i < len(values)-1 {
+			title = strings.TrimSpace("Caused by " + title)
+		}
+		if title != "" && !add(title) {
+			omitted += len(frames)
+			continue
+		}
+
+		for j, frame := range frames {
+			part := s.renderFrame(frame, format, 0)
+			// cut the code of the first frame rather than rendering none
+			if over := length + utf8.RuneCountInString(part) + 1 - (max - stacktraceOmitted); rendered == 0 && !full && over > 0 {
+				code := utf8.RuneCountInString(frameCode(frame, s.cfg.Stacktrace.ContextLines))
+				if code-over > 1 {
+					part = s.renderFrame(frame, format, code-over)
+				}
+			}
+			if !add(part) {
+				omitted += len(frames) - j
+				break
+			}
+			rendered++
+		}
+	}
+
+	if rendered == 0 {
+		return ""
+	}
+	if omitted == 1 {
+		parts = append(parts, "… 1 more frame")
+	} else if omitted > 1 {
+		parts = append(parts, fmt.Sprintf("… %d more frames", omitted))
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// exceptionTitle returns the type and value of the exception, eg: "ValueError: invalid literal"
+func exceptionTitle(value ExceptionValue) string {
+	switch {
+	case value.Type != "" && value.Value != "":
+		return value.Type + ": " + value.Value
+	case value.Type != "":
+		return value.Type
+	default:
+		return value.Value
+	}
+}
+
+// renderFrame renders the location and the code of the frame, the code is cut to maxCode characters if positive
+func (s *server) renderFrame(frame StacktraceFrame, format stacktraceFormat, maxCode int) string {
+	location := fmt.Sprintf("%s:%d", frame.Filename, frame.Lineno)
+	if frame.Filename == "" {
+		location = frame.AbsPath
+	}
+	header := format.code(location)
+	if frame.Function != "" {
+		header += " in " + format.code(frame.Function)
+	}
+
+	code := frameCode(frame, s.cfg.Stacktrace.ContextLines)
+	if code == "" {
+		return header
+	}
+	if maxCode > 0 {
+		code = truncate(code, maxCode)
+	}
+
+	return header + "\n" + format.block(code)
+}
+
+// frameCode returns the line of the frame or the numbered lines around it with the line marked
+func frameCode(frame StacktraceFrame, contextLines int) string {
+	if contextLines <= 0 || (len(frame.PreContext) == 0 && len(frame.PostContext) == 0) {
+		return strings.TrimRight(frame.ContextLine, "\n")
+	}
+
+	pre := frame.PreContext
+	if len(pre) > contextLines {
+		pre = pre[len(pre)-contextLines:]
+	}
+	post := frame.PostContext
+	if len(post) > contextLines {
+		post = post[:contextLines]
+	}
+
+	width := len(fmt.Sprint(frame.Lineno + len(post)))
+	lines := make([]string, 0, len(pre)+1+len(post))
+	for i, line := range pre {
+		lines = append(lines, fmt.Sprintf("  %*d  %v", width, frame.Lineno-len(pre)+i, line))
+	}
+	lines = append(lines, fmt.Sprintf("> %*d  %s", width, frame.Lineno, frame.ContextLine))
+	for i, line := range post {
+		lines = append(lines, fmt.Sprintf("  %*d  %v", width, frame.Lineno+1+i, line))
+	}
+
+	return strings.Join(lines, "\n")
+}
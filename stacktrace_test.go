@@ -0,0 +1,90 @@
+package slaxy
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRenderStacktrace(t *testing.T) {
+	s := New(Config{Stacktrace: StacktraceConfig{Frames: 2, InAppOnly: true, ContextLines: 1}}, NewNullLogger()).(*server)
+
+	hook := &Webhook{Event: SentryEvent{Exception: Exception{Values: []ExceptionValue{
+		{
+			Type:  "os.PathError",
+			Value: "open config.yml: no such file",
+			Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "config.go", Lineno: 12, Function: "load", InApp: true, ContextLine: "f, err := os.Open(path)"},
+			}},
+		},
+		{
+			Type:  "*errors.errorString",
+			Value: "startup failed",
+			Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "proc.go", Lineno: 250, Function: "runtime.main", ContextLine: "fn()"},
+				{Filename: "main.go", Lineno: 8, Function: "main", InApp: true, ContextLine: "run()"},
+				{Filename: "main.go", Lineno: 20, Function: "run", InApp: true, ContextLine: "app.start()"},
+				{
+					Filename: "app.go", Lineno: 99, Function: "start", InApp: true,
+					PreContext: []interface{}{"func start() {", "\tcfg, err := load()"}, ContextLine: "\tpanic(err)", PostContext: []interface{}{"}"},
+				},
+				{Filename: "panic.go", Lineno: 1038, Function: "runtime.gopanic", ContextLine: "fatalpanic(p)"},
+			}},
+		},
+	}}}}
+
+	want := strings.Join([]string{
+		"*errors.errorString: startup failed",
+		"`app.go:99` in `start`",
+		"```",
+		"   98  \tcfg, err := load()",
+		">  99  \tpanic(err)",
+		"  100  }",
+		"```",
+		"`main.go:20` in `run`",
+		"```",
+		"app.start()",
+		"```",
+		"Caused by os.PathError: open config.yml: no such file",
+		"`config.go:12` in `load`",
+		"```",
+		"f, err := os.Open(path)",
+		"```",
+		"… 1 more frame",
+	}, "\n")
+	if got := s.renderStacktrace(hook, markdownStacktrace, 2000); got != want {
+		t.Errorf("unexpected stacktrace\n%s\nwant\n%s", got, want)
+	}
+
+	jira := s.renderStacktrace(hook, jiraStacktrace, 2000)
+	if !strings.HasPrefix(jira, "*errors.errorString: startup failed\n{{app.go:99}} in {{start}}\n{noformat}\n") {
+		t.Errorf("unexpected jira stacktrace %q", jira)
+	}
+
+	// frames which don't fit are left out
+	short := s.renderStacktrace(hook, markdownStacktrace, 150)
+	if !strings.HasSuffix(short, "… 3 more frames") || strings.Contains(short, "Caused by") {
+		t.Errorf("unexpected short stacktrace %q", short)
+	}
+
+	if got := s.renderStacktrace(&Webhook{}, markdownStacktrace, 2000); got != "" {
+		t.Errorf("expected no stacktrace, got %q", got)
+	}
+}
+
+func TestRenderStacktraceCutsFirstFrame(t *testing.T) {
+	s := New(Config{}, NewNullLogger()).(*server)
+
+	hook := &Webhook{Event: SentryEvent{Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+		{Filename: "lib.go", Lineno: 1, ContextLine: "lib()"},
+		{Filename: "main.go", Lineno: 42, ContextLine: strings.Repeat("x", 5000)},
+	}}}}}}}
+
+	got := s.renderStacktrace(hook, markdownStacktrace, discordFieldValueMax)
+	if n := utf8.RuneCountInString(got); n > discordFieldValueMax {
+		t.Errorf("expected at most %d characters, got %d", discordFieldValueMax, n)
+	}
+	if !strings.HasPrefix(got, "`main.go:42`\n```\nxxx") || !strings.HasSuffix(got, "…\n```\n… 1 more frame") {
+		t.Errorf("expected the cut innermost frame, got %q", got)
+	}
+}
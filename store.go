@@ -0,0 +1,105 @@
+package slaxy
+
+import (
+	"sync"
+	"time"
+)
+
+// Store persists small values, eg: the slack message which was posted for a sentry issue.
+// Implement it to keep the mappings anywhere else.
+type Store interface {
+	// Get returns the value of the key and whether it was found, expired values are not found
+	Get(key string) (string, bool, error)
+	// Set stores the value of the key, it expires after ttl unless ttl is zero
+	Set(key, value string, ttl time.Duration) error
+	// Delete removes the key
+	Delete(key string) error
+	// Compact removes all expired values
+	Compact() error
+	// Close releases the resources of the store
+	Close() error
+}
+
+// memoryEntry is a value with its expiry, a zero expiry never expires
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// expired checks whether the entry is expired at the given time
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// memoryStore is a store which keeps everything in memory
+type memoryStore struct {
+	mu     sync.RWMutex
+	values map[string]memoryEntry
+}
+
+// NewMemoryStore returns a new store which keeps everything in memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		values: make(map[string]memoryEntry),
+	}
+}
+
+// Get returns the value of the key
+func (m *memoryStore) Get(key string) (string, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.values[key]
+	if !ok || entry.expired(time.Now()) {
+		return "", false, nil
+	}
+
+	return entry.value, true, nil
+}
+
+// Set stores the value of the key
+func (m *memoryStore) Set(key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	m.values[key] = memoryEntry{value: value, expires: expiry(ttl)}
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes the key
+func (m *memoryStore) Delete(key string) error {
+	m.mu.Lock()
+	delete(m.values, key)
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Compact removes all expired values
+func (m *memoryStore) Compact() error {
+	now := time.Now()
+
+	m.mu.Lock()
+	for key, entry := range m.values {
+		if entry.expired(now) {
+			delete(m.values, key)
+		}
+	}
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Close does nothing
+func (m *memoryStore) Close() error {
+	return nil
+}
+
+// expiry returns the expiry of a ttl, zero if it never expires
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(ttl)
+}
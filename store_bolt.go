@@ -0,0 +1,128 @@
+package slaxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket is the bucket all values are stored in
+var boltBucket = []byte("slaxy")
+
+// boltStore is a store which keeps everything in an embedded bolt database file
+type boltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens or creates the bolt database at path and returns a store backed by it
+func NewBoltStore(path string) (Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s, err: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket in store %s, err: %w", path, err)
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+// Get returns the value of the key
+func (b *boltStore) Get(key string) (string, bool, error) {
+	var value string
+	var found bool
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltBucket).Get([]byte(key))
+		if raw == nil {
+			return nil
+		}
+
+		entry, err := decodeBoltEntry(raw)
+		if err != nil {
+			return err
+		}
+		if entry.expired(time.Now()) {
+			return nil
+		}
+
+		value, found = entry.value, true
+		return nil
+	})
+
+	return value, found, err
+}
+
+// Set stores the value of the key
+func (b *boltStore) Set(key, value string, ttl time.Duration) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), encodeBoltEntry(memoryEntry{value: value, expires: expiry(ttl)}))
+	})
+}
+
+// Delete removes the key
+func (b *boltStore) Delete(key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+// Compact removes all expired values, bolt reuses the freed pages for new values
+func (b *boltStore) Compact() error {
+	now := time.Now()
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			entry, err := decodeBoltEntry(v)
+			// drop what can't be read anymore as well
+			if err == nil && !entry.expired(now) {
+				continue
+			}
+
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Close closes the database file
+func (b *boltStore) Close() error {
+	return b.db.Close()
+}
+
+// encodeBoltEntry encodes the entry as the unix nano expiry followed by the value
+func encodeBoltEntry(entry memoryEntry) []byte {
+	buf := make([]byte, 8+len(entry.value))
+	if !entry.expires.IsZero() {
+		binary.BigEndian.PutUint64(buf, uint64(entry.expires.UnixNano()))
+	}
+	copy(buf[8:], entry.value)
+
+	return buf
+}
+
+// decodeBoltEntry decodes an entry encoded by encodeBoltEntry
+func decodeBoltEntry(raw []byte) (memoryEntry, error) {
+	if len(raw) < 8 {
+		return memoryEntry{}, fmt.Errorf("invalid store entry of %d bytes", len(raw))
+	}
+
+	entry := memoryEntry{value: string(raw[8:])}
+	if expires := binary.BigEndian.Uint64(raw); expires != 0 {
+		entry.expires = time.Unix(0, int64(expires))
+	}
+
+	return entry, nil
+}
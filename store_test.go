@@ -0,0 +1,91 @@
+package slaxy
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	if err := store.Set("forever", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("expired", "b", time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("deleted", "c", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("deleted"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if value, ok, err := store.Get("forever"); err != nil || !ok || value != "a" {
+		t.Errorf("expected value a, got %q found=%v err=%v", value, ok, err)
+	}
+	if _, ok, err := store.Get("expired"); err != nil || ok {
+		t.Errorf("expired value should not be found, found=%v err=%v", ok, err)
+	}
+	if _, ok, err := store.Get("deleted"); err != nil || ok {
+		t.Errorf("deleted value should not be found, found=%v err=%v", ok, err)
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := store.Get("forever"); err != nil || !ok || value != "a" {
+		t.Errorf("compaction removed a valid value, got %q found=%v err=%v", value, ok, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+
+	testStore(t, store)
+
+	if n := len(store.(*memoryStore).values); n != 1 {
+		t.Errorf("expected one value after compaction, got %d", n)
+	}
+}
+
+func TestBoltStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slaxy.db")
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, store)
+
+	// values survive a restart
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if value, ok, err := store.Get("forever"); err != nil || !ok || value != "a" {
+		t.Errorf("expected value a after reopening, got %q found=%v err=%v", value, ok, err)
+	}
+}
+
+func TestStopTwice(t *testing.T) {
+	s := New(Config{StorePath: filepath.Join(t.TempDir(), "slaxy.db")}, NewNullLogger())
+	if err := setupTestServer(t, s.(*server)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("stopping again should do nothing, got %v", err)
+	}
+}
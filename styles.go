@@ -0,0 +1,141 @@
+package slaxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LevelStyle is how new alerts of a level are posted, empty parts keep the default style
+type LevelStyle struct {
+	// Color is a hex color like "#f43f20" of the message
+	Color string `mapstructure:"color"`
+	// Emoji prefixes the title, eg: "🔥", slack also understands shortcodes like ":fire:"
+	Emoji string `mapstructure:"emoji"`
+	// Mention notifies people in slack and discord: "here", "channel", a slack user group id like "S0123456789"
+	// or a raw mention like "<@&123456789>" of a discord role
+	Mention string `mapstructure:"mention"`
+}
+
+// colors of the levels, the others are the ones of the metric alerts
+const (
+	colorFatal = "#8b0000"
+	colorInfo  = "#2f80ed"
+)
+
+// defaultLevelStyles are the colors of the levels, other levels look like errors
+var defaultLevelStyles = map[string]LevelStyle{
+	"fatal":   {Color: colorFatal},
+	"error":   {Color: colorCritical},
+	"warning": {Color: colorWarning},
+	"info":    {Color: colorInfo},
+	"debug":   {Color: colorInfo},
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// validateLevelStyles checks the colors of the level styles
+func validateLevelStyles(styles map[string]LevelStyle) error {
+	for level, style := range styles {
+		if style.Color != "" && !hexColor.MatchString(style.Color) {
+			return fmt.Errorf("invalid color %q of level %s", style.Color, level)
+		}
+	}
+
+	return nil
+}
+
+// levelStyle returns the style of the level of the hook, the styles of the route override the global ones
+func (s *server) levelStyle(hook *Webhook, r *route) LevelStyle {
+	level := strings.ToLower(hook.level())
+
+	style, ok := defaultLevelStyles[level]
+	if !ok {
+		style = defaultLevelStyles["error"]
+	}
+	style = style.merge(lookupLevelStyle(s.cfg.LevelStyles, level))
+	if r != nil {
+		style = style.merge(lookupLevelStyle(r.levelStyles, level))
+	}
+
+	return style
+}
+
+// lookupLevelStyle returns the style of the level
+func lookupLevelStyle(styles map[string]LevelStyle, level string) LevelStyle {
+	style, _ := lookupFold(styles, level)
+	return style
+}
+
+// isFirstAlert reports whether the hook is the first alert of its issue at the destination, the level mention
+// of later ones would page the channel for every repeated event, hooks without an issue always are
+func (s *server) isFirstAlert(hook *Webhook, d destination) bool {
+	key := hook.issueKey()
+	if key == "" || !hook.isNewAlert() {
+		return true
+	}
+
+	key = "alerted:" + d.String() + ":" + key
+	if _, ok, err := s.store.Get(key); err != nil {
+		s.logger.Warnf("failed to load the alerts of %s: %s", d, err)
+	} else if ok {
+		return false
+	}
+	if err := s.store.Set(key, "", s.cfg.StoreTTL); err != nil {
+		s.logger.Warnf("failed to save the alerts of %s: %s", d, err)
+	}
+
+	return true
+}
+
+// merge overrides the style with the set parts of other
+func (style LevelStyle) merge(other LevelStyle) LevelStyle {
+	if other.Color != "" {
+		style.Color = other.Color
+	}
+	if other.Emoji != "" {
+		style.Emoji = other.Emoji
+	}
+	if other.Mention != "" {
+		style.Mention = other.Mention
+	}
+
+	return style
+}
+
+// title prefixes the title with the emoji
+func (style LevelStyle) title(title string) string {
+	if style.Emoji == "" || title == "" {
+		return title
+	}
+
+	return style.Emoji + " " + title
+}
+
+// slackMention returns the mention in slack markup, eg: "<!here>"
+func (style LevelStyle) slackMention() string {
+	switch {
+	case style.Mention == "":
+		return ""
+	case style.Mention == "here" || style.Mention == "channel" || style.Mention == "everyone":
+		return "<!" + style.Mention + ">"
+	case strings.HasPrefix(style.Mention, "<"):
+		return style.Mention
+	default:
+		return "<!subteam^" + style.Mention + ">"
+	}
+}
+
+// discordMention returns the mention in discord markup, slack user groups are left out
+func (style LevelStyle) discordMention() string {
+	switch {
+	case style.Mention == "here":
+		return "@here"
+	case style.Mention == "channel" || style.Mention == "everyone":
+		return "@everyone"
+	case strings.HasPrefix(style.Mention, "<@"):
+		return style.Mention
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,132 @@
+package slaxy
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLevelStyles(t *testing.T) {
+	s := New(Config{LevelStyles: map[string]LevelStyle{
+		"fatal":   {Emoji: "🔥", Mention: "channel"},
+		"warning": {Color: "#ffcc00"},
+	}}, NewNullLogger()).(*server)
+
+	r, err := compileRoute(&Route{Destinations: []string{"slack"}, LevelStyles: map[string]LevelStyle{
+		"Fatal": {Mention: "S0123456789"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		level string
+		r     *route
+		want  LevelStyle
+	}{
+		{"fatal", nil, LevelStyle{Color: colorFatal, Emoji: "🔥", Mention: "channel"}},
+		{"fatal", r, LevelStyle{Color: colorFatal, Emoji: "🔥", Mention: "S0123456789"}},
+		{"warning", r, LevelStyle{Color: "#ffcc00"}},
+		{"info", nil, LevelStyle{Color: colorInfo}},
+		{"critical", nil, LevelStyle{Color: colorCritical}},
+	}
+	for _, test := range tests {
+		if got := s.levelStyle(&Webhook{Level: test.level}, test.r); got != test.want {
+			t.Errorf("%s: expected %+v, got %+v", test.level, test.want, got)
+		}
+	}
+
+	if _, err := compileRoute(&Route{Destinations: []string{"slack"}, LevelStyles: map[string]LevelStyle{"error": {Color: "red"}}}); err == nil {
+		t.Error("expected an error of the invalid color")
+	}
+}
+
+func TestLevelStyleMentions(t *testing.T) {
+	tests := map[string][2]string{
+		"here":          {"<!here>", "@here"},
+		"channel":       {"<!channel>", "@everyone"},
+		"S0123456789":   {"<!subteam^S0123456789>", ""},
+		"<@&123456789>": {"<@&123456789>", "<@&123456789>"},
+		"":              {"", ""},
+	}
+	for mention, want := range tests {
+		style := LevelStyle{Mention: mention}
+		if got := style.slackMention(); got != want[0] {
+			t.Errorf("%q: expected slack mention %q, got %q", mention, want[0], got)
+		}
+		if got := style.discordMention(); got != want[1] {
+			t.Errorf("%q: expected discord mention %q, got %q", mention, want[1], got)
+		}
+	}
+}
+
+func TestLevelStyledMessages(t *testing.T) {
+	fake := newFakeSlack(t)
+	s := New(Config{LevelStyles: map[string]LevelStyle{
+		"fatal": {Emoji: "🔥", Mention: "here"},
+	}}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	fatal := &Webhook{ID: "1", Level: "fatal", Event: SentryEvent{Title: "boom"}}
+	warning := &Webhook{ID: "2", Level: "warning", Event: SentryEvent{Title: "slow"}}
+	for _, hook := range []*Webhook{fatal, warning} {
+		if err := s.slackHandleHook(context.Background(), hook, "alerts", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := fake.call(0).Form.Get("text"); got != "<!here>" {
+		t.Errorf("expected the fatal error to mention the channel, got %q", got)
+	}
+	if got := fake.call(1).Form.Get("text"); got != "" {
+		t.Errorf("expected the warning to stay quiet, got %q", got)
+	}
+
+	attachment := s.renderAttachment(fatal, nil)
+	if attachment.Title != "🔥 boom" || attachment.Color != colorFatal {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+	if attachment := s.renderAttachment(warning, nil); attachment.Title != "slow" || attachment.Color != colorWarning {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+
+	message := s.renderDiscordMessage(context.Background(), fatal, nil, true)
+	if message.Content != "@here" || message.Embeds[0].Title != "🔥 boom" || message.Embeds[0].Color != colorToInt(colorFatal) {
+		t.Errorf("unexpected discord message %+v %+v", message, message.Embeds[0])
+	}
+
+	// lifecycle changes don't mention anyone
+	resolved := &Webhook{Resource: resourceIssue, Action: issueResolved, Level: "fatal", Issue: &SentryIssue{}, Event: SentryEvent{Title: "boom"}}
+	if message := s.renderDiscordMessage(context.Background(), resolved, nil, true); message.Content != "" {
+		t.Errorf("expected no mention of the resolution, got %q", message.Content)
+	}
+}
+
+func TestLevelMentionOnlyOnFirstAlert(t *testing.T) {
+	fake := newFakeSlack(t)
+	s := New(Config{SlackThreads: true, LevelStyles: map[string]LevelStyle{
+		"fatal": {Mention: "channel"},
+	}}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	hook := &Webhook{ID: "1", Level: "fatal", Event: SentryEvent{Title: "boom"}}
+	for i := 0; i < 2; i++ {
+		if err := s.slackHandleHook(context.Background(), hook, "alerts", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := fake.call(0).Form.Get("text"); got != "<!channel>" {
+		t.Errorf("expected the first alert to mention the channel, got %q", got)
+	}
+	if got := fake.call(1).Form.Get("text"); got != "" {
+		t.Errorf("expected the repeated alert in the thread to stay quiet, got %q", got)
+	}
+
+	// discord has no threads, the repeated events are posted without the mention as well
+	discord := destination{kind: destinationDiscord, target: "ops"}
+	first := s.renderDiscordMessage(context.Background(), hook, nil, s.isFirstAlert(hook, discord))
+	repeated := s.renderDiscordMessage(context.Background(), hook, nil, s.isFirstAlert(hook, discord))
+	if first.Content != "@everyone" || repeated.Content != "" {
+		t.Errorf("expected only the first discord message to mention, got %q and %q", first.Content, repeated.Content)
+	}
+}
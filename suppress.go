@@ -0,0 +1,263 @@
+package slaxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// suppression keys
+const (
+	suppressByIssue   = "issue"
+	suppressByCulprit = "culprit"
+)
+
+const resourceSummary = "summary"
+
+// SuppressionConfig configures deduplication and rate limiting of repeated alerts
+type SuppressionConfig struct {
+	// Window in which repeated alerts with the same key are suppressed, disabled if zero
+	Window time.Duration `mapstructure:"window"`
+	// Key identifies repeated alerts, "issue" (default), "culprit" or a template like "{{ .ProjectSlug }}/{{ .Event.Title }}"
+	Key string `mapstructure:"key"`
+	// RateLimit is the number of alerts per minute allowed per destination, unlimited if zero
+	RateLimit float64 `mapstructure:"rate-limit"`
+	// Burst is the number of alerts allowed at once per destination, defaults to the rate limit
+	Burst int `mapstructure:"burst"`
+	// SummaryInterval is how often summaries of the suppressed alerts are posted, defaults to 10m
+	SummaryInterval time.Duration `mapstructure:"summary-interval"`
+}
+
+// suppressor decides which alerts are forwarded and remembers the suppressed ones
+type suppressor struct {
+	cfg SuppressionConfig
+	key *template.Template
+	now func() time.Time
+
+	mu         sync.Mutex
+	seen       map[string]time.Time
+	buckets    map[string]*tokenBucket
+	suppressed map[string]*suppressedAlerts
+}
+
+// suppressedAlerts counts the suppressed alerts of one key in a destination
+type suppressedAlerts struct {
+	path  destination
+	route *route
+	hook  *Webhook
+	count int
+}
+
+// tokenBucket is a token bucket rate limiter
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newSuppressor creates a suppressor, it returns nil if neither deduplication nor rate limiting is configured
+func newSuppressor(cfg SuppressionConfig) (*suppressor, error) {
+	if cfg.Window <= 0 && cfg.RateLimit <= 0 {
+		return nil, nil
+	}
+
+	if cfg.SummaryInterval <= 0 {
+		cfg.SummaryInterval = 10 * time.Minute
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = int(cfg.RateLimit)
+		if cfg.Burst < 1 {
+			cfg.Burst = 1
+		}
+	}
+
+	p := &suppressor{
+		cfg:        cfg,
+		now:        time.Now,
+		seen:       make(map[string]time.Time),
+		buckets:    make(map[string]*tokenBucket),
+		suppressed: make(map[string]*suppressedAlerts),
+	}
+
+	switch cfg.Key {
+	case "", suppressByIssue, suppressByCulprit:
+	default:
+		tpl, err := template.New("suppression-key").Parse(cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid suppression key template, err: %w", err)
+		}
+		p.key = tpl
+	}
+
+	return p, nil
+}
+
+// allow reports whether the hook should be forwarded to the destination it was routed to by r,
+// suppressed hooks are remembered for the summary
+func (p *suppressor) allow(hook *Webhook, path destination, r *route) (bool, string) {
+	// status changes are never repeated
+	if hook.isStatusChange() || hook.MetricAlert != nil || hook.Resource == resourceSummary {
+		return true, ""
+	}
+
+	channel := path.String()
+	key := channel + ":" + p.keyOf(hook)
+	now := p.now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cfg.Window > 0 {
+		if last, ok := p.seen[key]; ok && now.Sub(last) < p.cfg.Window {
+			p.remember(key, hook, path, r)
+			return false, "duplicate"
+		}
+	}
+
+	if p.cfg.RateLimit > 0 && !p.take(channel, now) {
+		p.remember(key, hook, path, r)
+		return false, "rate_limit"
+	}
+
+	if p.cfg.Window > 0 {
+		p.seen[key] = now
+	}
+
+	return true, ""
+}
+
+// keyOf returns the suppression key of the hook
+func (p *suppressor) keyOf(hook *Webhook) string {
+	if p.key != nil {
+		buf := bytes.NewBuffer(nil)
+		if err := p.key.Execute(buf, hook); err == nil {
+			return buf.String()
+		}
+	}
+
+	if p.cfg.Key == suppressByCulprit {
+		return hook.ProjectSlug + "/" + hook.Culprit
+	}
+
+	if key := hook.issueKey(); key != "" {
+		return key
+	}
+
+	return hook.ProjectSlug + "/" + hook.Title()
+}
+
+// take takes a token of the channel's bucket
+func (p *suppressor) take(channel string, now time.Time) bool {
+	bucket, ok := p.buckets[channel]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(p.cfg.Burst), last: now}
+		p.buckets[channel] = bucket
+	}
+
+	// refill
+	bucket.tokens += now.Sub(bucket.last).Minutes() * p.cfg.RateLimit
+	if bucket.tokens > float64(p.cfg.Burst) {
+		bucket.tokens = float64(p.cfg.Burst)
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+
+	return true
+}
+
+// remember counts the suppressed hook
+func (p *suppressor) remember(key string, hook *Webhook, path destination, r *route) {
+	alerts, ok := p.suppressed[key]
+	if !ok {
+		alerts = &suppressedAlerts{path: path, route: r, hook: hook}
+		p.suppressed[key] = alerts
+	}
+	alerts.count++
+}
+
+// flush returns all suppressed alerts since the last flush
+func (p *suppressor) flush() []*suppressedAlerts {
+	now := p.now()
+
+	p.mu.Lock()
+	suppressed := p.suppressed
+	p.suppressed = make(map[string]*suppressedAlerts)
+
+	// forget what is outside the window anyway
+	for key, last := range p.seen {
+		if now.Sub(last) >= p.cfg.Window {
+			delete(p.seen, key)
+		}
+	}
+	p.mu.Unlock()
+
+	keys := make([]string, 0, len(suppressed))
+	for key := range suppressed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	flushed := make([]*suppressedAlerts, 0, len(keys))
+	for _, key := range keys {
+		flushed = append(flushed, suppressed[key])
+	}
+
+	return flushed
+}
+
+// summary returns the summary message of the suppressed alerts,
+// it keeps the content of the first suppressed alert to be routed alike
+func (a *suppressedAlerts) summary(interval time.Duration) *Webhook {
+	summary := *a.hook
+	summary.Resource = resourceSummary
+	summary.Message = fmt.Sprintf("%d more occurrences of %s suppressed in the last %s", a.count, a.hook.Title(), formatDuration(interval))
+
+	return &summary
+}
+
+// isSuppressible checks whether alerts to the destination may be suppressed, paging and ticket
+// destinations always get them so a rate limited channel never swallows a page
+func isSuppressible(d destination) bool {
+	return !isPagingDestination(d) && !isTicketDestination(d)
+}
+
+// formatDuration formats a duration without needless zero units, eg: "10m" instead of "10m0s"
+func formatDuration(d time.Duration) string {
+	str := d.String()
+	if strings.HasSuffix(str, "m0s") {
+		str = str[:len(str)-2]
+	}
+	if strings.HasSuffix(str, "h0m") {
+		str = str[:len(str)-2]
+	}
+
+	return str
+}
+
+// postSummaries periodically posts the summaries of the suppressed alerts until the server is stopped
+func (s *server) postSummaries() {
+	ticker := time.NewTicker(s.suppressor.cfg.SummaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			for _, alerts := range s.suppressor.flush() {
+				summary := alerts.summary(s.suppressor.cfg.SummaryInterval)
+				if err := s.notifyDestination(context.Background(), summary, alerts.path, alerts.route); err != nil {
+					s.errChan <- fmt.Errorf("failed to post summary, err: %w", err)
+				}
+			}
+		}
+	}
+}
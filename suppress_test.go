@@ -0,0 +1,138 @@
+package slaxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var alertsPath = destination{kind: destinationSlack, target: "alerts"}
+
+func TestSuppressorWindow(t *testing.T) {
+	p, err := newSuppressor(SuppressionConfig{Window: 10 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	hook := &Webhook{ID: "1", Event: SentryEvent{Title: "boom"}}
+	if ok, _ := p.allow(hook, alertsPath, nil); !ok {
+		t.Fatal("first alert should be forwarded")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, reason := p.allow(hook, alertsPath, nil); ok || reason != "duplicate" {
+			t.Fatalf("repeated alert should be suppressed as duplicate, got %v %q", ok, reason)
+		}
+	}
+	if ok, _ := p.allow(hook, destination{kind: destinationSlack, target: "other"}, nil); !ok {
+		t.Error("the same alert in another channel should be forwarded")
+	}
+
+	flushed := p.flush()
+	if len(flushed) != 1 || flushed[0].count != 3 || flushed[0].path != alertsPath {
+		t.Fatalf("unexpected suppressed alerts %+v", flushed)
+	}
+	if got := flushed[0].summary(10 * time.Minute).Message; got != "3 more occurrences of boom suppressed in the last 10m" {
+		t.Errorf("unexpected summary %q", got)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if ok, _ := p.allow(hook, alertsPath, nil); !ok {
+		t.Error("alert after the window should be forwarded")
+	}
+}
+
+func TestSuppressorTemplateKey(t *testing.T) {
+	p, err := newSuppressor(SuppressionConfig{Window: time.Minute, Key: "{{ .ProjectSlug }}/{{ .Event.Title }}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := p.allow(&Webhook{ID: "1", ProjectSlug: "backend", Event: SentryEvent{Title: "boom"}}, alertsPath, nil); !ok {
+		t.Fatal("first alert should be forwarded")
+	}
+	if ok, _ := p.allow(&Webhook{ID: "2", ProjectSlug: "backend", Event: SentryEvent{Title: "boom"}}, alertsPath, nil); ok {
+		t.Error("alert with the same fingerprint should be suppressed")
+	}
+}
+
+func TestSuppressorRateLimit(t *testing.T) {
+	p, err := newSuppressor(SuppressionConfig{RateLimit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if ok, _ := p.allow(&Webhook{ID: string(rune('a' + i))}, alertsPath, nil); ok {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("expected 2 alerts within the burst, got %d", allowed)
+	}
+
+	now = now.Add(30 * time.Second)
+	if ok, _ := p.allow(&Webhook{ID: "z"}, alertsPath, nil); !ok {
+		t.Error("bucket should be refilled after 30s")
+	}
+}
+
+func TestSuppressorDisabled(t *testing.T) {
+	p, err := newSuppressor(SuppressionConfig{})
+	if err != nil || p != nil {
+		t.Errorf("expected no suppressor, got %v, err=%v", p, err)
+	}
+}
+
+func TestRateLimitedPathStillPages(t *testing.T) {
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer srv.Close()
+
+	s := New(Config{
+		PagerDutyURL:         srv.URL + "/pagerduty",
+		PagerDutyRoutingKeys: map[string]string{"backend": "routing-key"},
+		Routes:               []Route{{Level: "fatal", Destinations: []string{"pagerduty:backend", "http:chat"}}},
+		Suppression:          SuppressionConfig{RateLimit: 1},
+	}, NewNullLogger()).(*server)
+	var err error
+	if s.routes, err = compileRoutes(s.cfg.Routes); err != nil {
+		t.Fatal(err)
+	}
+	if s.suppressor, err = newSuppressor(s.cfg.Suppression); err != nil {
+		t.Fatal(err)
+	}
+	if s.httpDestinations, err = compileHTTPDestinations(map[string]HTTPDestination{"chat": {URL: srv.URL + "/chat"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		hook := &Webhook{ID: id, ProjectName: "backend", Level: "fatal", Event: SentryEvent{Title: "boom " + id}}
+		if err := s.notify(context.Background(), hook, alertsPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if requests["/chat"] != 1 {
+		t.Errorf("expected the chat destination to be rate limited to 1 alert, got %d", requests["/chat"])
+	}
+	if requests["/pagerduty"] != 3 {
+		t.Errorf("expected every alert to be paged, got %d pages", requests["/pagerduty"])
+	}
+
+	flushed := s.suppressor.flush()
+	if len(flushed) != 2 || flushed[0].path != (destination{kind: destinationHTTP, target: "chat"}) || flushed[0].route == nil {
+		t.Errorf("unexpected suppressed alerts %+v", flushed)
+	}
+}
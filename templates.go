@@ -0,0 +1,258 @@
+package slaxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// templateFuncs are the helpers available in user-defined templates
+var templateFuncs = template.FuncMap{
+	// json encodes the value, eg: {"title": {{ json .Title }}}
+	"json": func(v interface{}) (string, error) {
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	},
+	// tag returns the first value of the event tag
+	"tag": func(hook *Webhook, key string) string {
+		return hook.tagValue(key)
+	},
+	// frame returns the innermost frame of the latest exception, which is rendered first by default,
+	// nil without a stacktrace
+	"frame": func(hook *Webhook) *StacktraceFrame {
+		return hook.topFrame()
+	},
+	// frames returns all frames of the latest exception, the innermost frame is the last one
+	"frames": func(hook *Webhook) []StacktraceFrame {
+		return hook.latestFrames()
+	},
+	// formatTime formats a unix timestamp like .Event.Timestamp with the go time layout, eg: "2006-01-02 15:04"
+	"formatTime": func(layout string, ts float64) string {
+		if ts == 0 {
+			return ""
+		}
+		return time.Unix(int64(ts), 0).UTC().Format(layout)
+	},
+	// truncate shortens the text to at most n characters
+	"truncate": func(n int, text string) string {
+		return truncate(text, n)
+	},
+	// default returns the fallback if the value is empty
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"join":  strings.Join,
+}
+
+// parseTemplate parses the user-defined template with the template helpers
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// MessageTemplate overrides parts of the message layout of the slack and discord destinations of a route.
+// All parts are go templates of the webhook, empty parts keep the built-in layout.
+// Only new alerts are templated, lifecycle changes, metric alerts and summaries keep their layout.
+type MessageTemplate struct {
+	Title string `mapstructure:"title"`
+	Text  string `mapstructure:"text"`
+	// Color renders a hex color like "#f43f20"
+	Color  string `mapstructure:"color"`
+	Footer string `mapstructure:"footer"`
+	// Fields replace the built-in fields, fields which render empty are left out
+	Fields []FieldTemplate `mapstructure:"fields"`
+	// TagFields appends the not excluded event tags as fields like the built-in layout
+	TagFields bool `mapstructure:"tag-fields"`
+}
+
+// FieldTemplate is a field of a MessageTemplate
+type FieldTemplate struct {
+	Title string `mapstructure:"title"`
+	Value string `mapstructure:"value"`
+	Short bool   `mapstructure:"short"`
+}
+
+// messageTemplate is a compiled MessageTemplate
+type messageTemplate struct {
+	title     *template.Template
+	text      *template.Template
+	color     *template.Template
+	footer    *template.Template
+	fields    []fieldTemplate
+	tagFields bool
+}
+
+type fieldTemplate struct {
+	title *template.Template
+	value *template.Template
+	short bool
+}
+
+// sampleHook is the webhook templates are validated with on start
+var sampleHook = &Webhook{
+	Issue: &SentryIssue{},
+	Event: SentryEvent{
+		Timestamp: 1645672116,
+		Tags:      []SentryTag{{"server_name", "web-1"}},
+		Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+			{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+		}}}}},
+	},
+}
+
+// compileMessageTemplate parses the templates and renders them once to catch errors like unknown fields on start
+func compileMessageTemplate(cfg *MessageTemplate) (*messageTemplate, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	parse := func(name, text string) (*template.Template, error) {
+		if text == "" {
+			return nil, nil
+		}
+
+		tpl, err := parseTemplate(name, text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template, err: %w", name, err)
+		}
+		if _, err := render(tpl, sampleHook); err != nil {
+			return nil, fmt.Errorf("invalid %s template, err: %w", name, err)
+		}
+
+		return tpl, nil
+	}
+
+	var err error
+	compiled := &messageTemplate{tagFields: cfg.TagFields}
+	if compiled.title, err = parse("title", cfg.Title); err != nil {
+		return nil, err
+	}
+	if compiled.text, err = parse("text", cfg.Text); err != nil {
+		return nil, err
+	}
+	if compiled.color, err = parse("color", cfg.Color); err != nil {
+		return nil, err
+	}
+	if compiled.footer, err = parse("footer", cfg.Footer); err != nil {
+		return nil, err
+	}
+
+	for i, field := range cfg.Fields {
+		if field.Title == "" || field.Value == "" {
+			return nil, fmt.Errorf("field #%d needs a title and a value", i+1)
+		}
+
+		f := fieldTemplate{short: field.Short}
+		if f.title, err = parse(fmt.Sprintf("field #%d title", i+1), field.Title); err != nil {
+			return nil, err
+		}
+		if f.value, err = parse(fmt.Sprintf("field #%d value", i+1), field.Value); err != nil {
+			return nil, err
+		}
+		compiled.fields = append(compiled.fields, f)
+	}
+
+	return compiled, nil
+}
+
+// render executes the template with the hook
+func render(tpl *template.Template, hook *Webhook) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := tpl.Execute(buf, hook); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// isTemplated reports whether the hook is a new alert which is rendered by the message template of the route
+func isTemplated(hook *Webhook, r *route) bool {
+	return r != nil && r.template != nil && hook.isNewAlert()
+}
+
+// renderAttachment will create the slack message attachment with the message template of the route
+// or the built-in layout, new alerts get the level style of the route
+func (s *server) renderAttachment(hook *Webhook, r *route) slack.Attachment {
+	attachment := s.createAttachment(hook)
+	if !hook.isNewAlert() {
+		return attachment
+	}
+
+	style := s.levelStyle(hook, r)
+	attachment.Color = style.Color
+	if isTemplated(hook, r) {
+		templated, err := s.applyMessageTemplate(attachment, hook, r.template)
+		if err != nil {
+			s.logger.Warnf("failed to render the message template of route %s, using the built-in layout: %s", r.name, err)
+		} else {
+			attachment = templated
+		}
+	}
+	attachment.Title = style.title(attachment.Title)
+
+	return attachment
+}
+
+// applyMessageTemplate overrides the templated parts of the attachment
+func (s *server) applyMessageTemplate(attachment slack.Attachment, hook *Webhook, tpl *messageTemplate) (slack.Attachment, error) {
+	var err error
+	parts := []struct {
+		tpl *template.Template
+		dst *string
+	}{
+		{tpl.title, &attachment.Title},
+		{tpl.text, &attachment.Text},
+		{tpl.color, &attachment.Color},
+		{tpl.footer, &attachment.Footer},
+	}
+	for _, part := range parts {
+		if part.tpl == nil {
+			continue
+		}
+		if *part.dst, err = render(part.tpl, hook); err != nil {
+			return attachment, err
+		}
+		*part.dst = strings.TrimSpace(*part.dst)
+	}
+
+	if len(tpl.fields) == 0 && !tpl.tagFields {
+		return attachment, nil
+	}
+
+	var fields []slack.AttachmentField
+	for _, field := range tpl.fields {
+		title, err := render(field.title, hook)
+		if err != nil {
+			return attachment, err
+		}
+		value, err := render(field.value, hook)
+		if err != nil {
+			return attachment, err
+		}
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		fields = append(fields, slack.AttachmentField{
+			Title: strings.TrimSpace(title),
+			Value: strings.TrimSpace(value),
+			Short: field.short,
+		})
+	}
+
+	if tpl.tagFields {
+		fields = append(fields, s.tagFields(hook)...)
+	}
+	attachment.Fields = fields
+
+	return attachment, nil
+}
@@ -0,0 +1,107 @@
+package slaxy
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	r, err := compileRoute(&Route{
+		Name:         "backend",
+		Destinations: []string{"slack"},
+		Template: &MessageTemplate{
+			Title:  `{{ upper .ProjectName }}: {{ truncate 10 .Event.Title }}`,
+			Text:   `{{ with frame . }}{{ .Filename }}:{{ .Lineno }}{{ end }}`,
+			Color:  `{{ if eq .Level "fatal" }}#000000{{ else }}#f2b036{{ end }}`,
+			Footer: `seen {{ formatTime "2006-01-02 15:04" .Event.Timestamp }}`,
+			Fields: []FieldTemplate{
+				{Title: "Server", Value: `{{ tag . "server_name" }}`, Short: true},
+				{Title: "Release", Value: `{{ .Event.Release }}`, Short: true},
+			},
+			TagFields: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(Config{}, NewNullLogger()).(*server)
+	s.excludedFields = []*regexp.Regexp{regexp.MustCompile("^sentry:.*$")}
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/1/",
+		Event: SentryEvent{
+			Title:     "index out of range",
+			Timestamp: 1645672116.893372,
+			Tags:      []SentryTag{{"server_name", "web-1"}, {"sentry:user", "jane"}},
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+			}}}}},
+		},
+	}
+
+	attachment := s.renderAttachment(hook, r)
+	if attachment.Title != "BACKEND: index out…" || attachment.TitleLink != hook.URL {
+		t.Errorf("unexpected title %q", attachment.Title)
+	}
+	if attachment.Text != "main.go:42" || attachment.Color != "#f2b036" || attachment.Footer != "seen 2022-02-24 03:08" {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+
+	// the empty release is left out, the tags follow the fields
+	if len(attachment.Fields) != 2 || attachment.Fields[0].Value != "web-1" || attachment.Fields[1].Title != "Server Name" {
+		t.Errorf("unexpected fields %+v", attachment.Fields)
+	}
+
+	message := s.renderDiscordMessage(context.Background(), hook, r, true)
+	if len(message.Embeds) != 1 || message.Embeds[0].Title != attachment.Title || message.Embeds[0].Color != colorToInt("#f2b036") {
+		t.Errorf("unexpected discord message %+v", message)
+	}
+
+	// lifecycle changes and destinations without a route keep the built-in layout
+	resolved := &Webhook{Resource: resourceIssue, Action: issueResolved, Issue: &SentryIssue{}, Event: SentryEvent{Title: "boom"}}
+	if got := s.renderAttachment(resolved, r); got.Color != colorResolved {
+		t.Errorf("status change should not be templated, got %+v", got)
+	}
+	if got := s.renderAttachment(hook, nil); got.Title != hook.Title() || got.Color != colorCritical {
+		t.Errorf("built-in layout expected, got %+v", got)
+	}
+}
+
+func TestMessageTemplateValidation(t *testing.T) {
+	tests := map[string]*MessageTemplate{
+		"syntax error":     {Title: `{{ .Title`},
+		"unknown field":    {Text: `{{ .Nope }}`},
+		"unknown function": {Color: `{{ nope }}`},
+		"wrong arguments":  {Footer: `{{ truncate .Title }}`},
+		"field title":      {Fields: []FieldTemplate{{Value: `{{ .Culprit }}`}}},
+	}
+
+	for name, tpl := range tests {
+		if _, err := compileRoute(&Route{Destinations: []string{"slack"}, Template: tpl}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFrameOfChainedExceptions(t *testing.T) {
+	hook := &Webhook{Event: SentryEvent{Exception: Exception{Values: []ExceptionValue{
+		{Stacktrace: Stacktrace{Frames: []StacktraceFrame{{Filename: "cause.go", Lineno: 1}}}},
+		{Stacktrace: Stacktrace{Frames: []StacktraceFrame{{Filename: "main.go", Lineno: 2}, {Filename: "handler.go", Lineno: 3}}}},
+		{},
+	}}}}
+
+	// the latest exception with a stacktrace is rendered first
+	if frame := hook.topFrame(); frame == nil || frame.Filename != "handler.go" {
+		t.Errorf("expected the innermost frame of the latest exception, got %+v", frame)
+	}
+	if frames := hook.latestFrames(); len(frames) != 2 || frames[0].Filename != "main.go" {
+		t.Errorf("unexpected frames %+v", frames)
+	}
+	if frame := (&Webhook{}).topFrame(); frame != nil {
+		t.Errorf("expected no frame without a stacktrace, got %+v", frame)
+	}
+}
@@ -0,0 +1,31 @@
+package slaxy
+
+import "strings"
+
+// truncate shortens the text to at most max characters, it is empty if max isn't positive
+func truncate(text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+
+	return string(runes[:max-1]) + "…"
+}
+
+// truncateMrkdwn shortens the text to at most max characters and closes a code block it cut off
+func truncateMrkdwn(text string, max int) string {
+	if len([]rune(text)) <= max {
+		return text
+	}
+
+	text = truncate(text, max-4)
+	if strings.Count(text, "```")%2 == 1 {
+		text += "\n```"
+	}
+
+	return text
+}
@@ -0,0 +1,27 @@
+package slaxy
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text     string
+		max      int
+		expected string
+	}{
+		{"boom", 4, "boom"},
+		{"kaboom", 4, "kab…"},
+		{"äöüß", 3, "äö…"},
+		{"boom", 0, ""},
+		{"boom", -1, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.text, tt.max); got != tt.expected {
+			t.Errorf("truncate(%q, %d) = %q, expected %q", tt.text, tt.max, got, tt.expected)
+		}
+	}
+
+	if got := truncateMrkdwn("```\npanic(err)\n```", 12); got != "```\npan…\n```" {
+		t.Errorf("unexpected truncated code block %q", got)
+	}
+}
@@ -0,0 +1,188 @@
+package slaxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// ticket is an issue of a tracker like jira or github which was opened for a sentry issue
+type ticket struct {
+	// Destination is the tracker the ticket was opened at, eg: "jira:backend"
+	Destination string `json:"destination"`
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+}
+
+// keyedMutex is a mutex per key, the mutexes are removed once nobody holds or waits for them
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock is the mutex of a key and the number of its holders and waiters
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the mutex of the key and returns the function unlocking it
+func (m *keyedMutex) lock(key string) func() {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = map[string]*keyedLock{}
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// isTicketDestination reports whether the destination opens tickets, they are notified first
+// so the other destinations can link to the tickets
+func isTicketDestination(d destination) bool {
+	return d.kind == destinationJira || d.kind == destinationGitHub
+}
+
+// sortTicketDestinations moves the ticket destinations to the front
+func sortTicketDestinations(destinations []destination) {
+	sort.SliceStable(destinations, func(i, j int) bool {
+		return isTicketDestination(destinations[i]) && !isTicketDestination(destinations[j])
+	})
+}
+
+// ticketsKey is the store key of the tickets of the issue of the hook
+func ticketsKey(hook *Webhook) string {
+	return "tickets:" + hook.issueKey()
+}
+
+// tickets returns the tickets which were opened for the issue of the hook
+func (s *server) tickets(hook *Webhook) []ticket {
+	if hook.issueKey() == "" {
+		return nil
+	}
+
+	value, ok, err := s.store.Get(ticketsKey(hook))
+	if err != nil {
+		s.logger.Warnf("failed to load tickets of %s: %s", hook.issueKey(), err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	var tickets []ticket
+	if err := json.Unmarshal([]byte(value), &tickets); err != nil {
+		s.logger.Warnf("failed to decode tickets of %s: %s", hook.issueKey(), err)
+		return nil
+	}
+
+	return tickets
+}
+
+// ticketOf returns the ticket which was opened at the destination for the issue of the hook
+func (s *server) ticketOf(hook *Webhook, d destination) *ticket {
+	for _, t := range s.tickets(hook) {
+		if strings.EqualFold(t.Destination, d.String()) {
+			return &t
+		}
+	}
+
+	return nil
+}
+
+// saveTicket stores the ticket of the issue of the hook
+func (s *server) saveTicket(hook *Webhook, t ticket) error {
+	value, err := json.Marshal(append(s.tickets(hook), t))
+	if err != nil {
+		return err
+	}
+
+	return s.store.Set(ticketsKey(hook), string(value), s.cfg.StoreTTL)
+}
+
+// openTicket opens a ticket for the issue of the hook with create unless the destination has one already
+func (s *server) openTicket(hook *Webhook, d destination, create func() (ticket, error)) error {
+	// lifecycle changes and summaries don't get tickets, neither do hooks without an issue to remember them by
+	if hook.issueKey() == "" || hook.isStatusChange() || hook.Resource == resourceSummary {
+		return nil
+	}
+
+	// don't open two tickets for an issue which is reported twice at once, other issues don't wait for the tracker
+	unlock := s.ticketLocks.lock(hook.issueKey())
+	defer unlock()
+
+	if existing := s.ticketOf(hook, d); existing != nil {
+		s.logger.Debugf("%s has ticket %s already", hook.issueKey(), existing.Key)
+		return nil
+	}
+
+	t, err := create()
+	if err != nil {
+		return err
+	}
+	t.Destination = d.String()
+
+	s.logger.Infof("Opened ticket %s for %s", t.Key, hook.issueKey())
+	return s.saveTicket(hook, t)
+}
+
+// ticketDescription is the plain text description of a ticket with the title, culprit and sentry link,
+// the trackers add the stacktrace frame in their markup
+func ticketDescription(hook *Webhook) string {
+	var lines []string
+	lines = append(lines, hook.Title(), "")
+	if hook.Culprit != "" {
+		lines = append(lines, "Culprit: "+hook.Culprit)
+	}
+	if hook.ProjectName != "" {
+		lines = append(lines, "Project: "+hook.ProjectName)
+	}
+	if env := hook.environment(); env != "" {
+		lines = append(lines, "Environment: "+env)
+	}
+	if level := hook.level(); level != "" {
+		lines = append(lines, "Level: "+level)
+	}
+	if hook.URL != "" {
+		lines = append(lines, "Sentry: "+hook.URL)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ticketFields returns the slack attachment fields linking to the tickets of the issue of the hook
+func (s *server) ticketFields(hook *Webhook) []slack.AttachmentField {
+	tickets := s.tickets(hook)
+
+	fields := make([]slack.AttachmentField, 0, len(tickets))
+	for _, t := range tickets {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Ticket",
+			Value: fmt.Sprintf("<%s|%s>", t.URL, t.Key),
+			Short: true,
+		})
+	}
+
+	return fields
+}
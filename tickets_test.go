@@ -0,0 +1,150 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestJiraTicketsLinkedInSlack(t *testing.T) {
+	var issues []jiraIssue
+	jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if user, token, _ := r.BasicAuth(); user != "bot@acme.com" || token != "api-token" {
+			t.Errorf("unexpected auth %s:%s", user, token)
+		}
+
+		var issue jiraIssue
+		if err := json.NewDecoder(r.Body).Decode(&issue); err != nil {
+			t.Error(err)
+		}
+		issues = append(issues, issue)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "10000", "key": "BACK-7"}`))
+	}))
+	defer jira.Close()
+
+	fake := newFakeSlack(t)
+	s := New(Config{
+		JiraProjects: map[string]JiraProject{
+			"backend": {URL: jira.URL + "/", Username: "bot@acme.com", Token: "api-token", Project: "BACK", Labels: []string{"sentry"}},
+		},
+		Routes: []Route{{Destinations: []string{"slack", "jira:backend"}}},
+	}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+	routes, err := compileRoutes(s.cfg.Routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.routes = routes
+
+	hook := &Webhook{
+		ID:          "42",
+		ProjectName: "backend",
+		Culprit:     "main.run",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/42/",
+		Event: SentryEvent{
+			Title: "boom",
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+			}}}}},
+		},
+	}
+	path := destination{kind: destinationSlack, target: "C0123"}
+	for i := 0; i < 2; i++ {
+		if err := s.notify(context.Background(), hook, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(issues) != 1 {
+		t.Fatalf("expected one jira issue for a repeated sentry issue, got %d", len(issues))
+	}
+	fields := issues[0].Fields
+	if fields.Project.Key != "BACK" || fields.IssueType.Name != "Bug" || fields.Summary != "boom" || len(fields.Labels) != 1 {
+		t.Errorf("unexpected issue %+v", fields)
+	}
+	for _, expected := range []string{"Culprit: main.run", "main.go:42", "{noformat}\npanic(err)\n{noformat}", hook.URL} {
+		if !strings.Contains(fields.Description, expected) {
+			t.Errorf("description does not contain %q: %s", expected, fields.Description)
+		}
+	}
+
+	// both slack messages link to the ticket, the first one was posted after the ticket was opened
+	for i := 0; i < 2; i++ {
+		var attachments []slack.Attachment
+		if err := json.Unmarshal([]byte(fake.call(i).Form.Get("attachments")), &attachments); err != nil {
+			t.Fatal(err)
+		}
+
+		var linked bool
+		for _, field := range attachments[0].Fields {
+			if field.Title == "Ticket" && field.Value == "<"+jira.URL+"/browse/BACK-7|BACK-7>" {
+				linked = true
+			}
+		}
+		if !linked {
+			t.Errorf("message %d does not link to the ticket: %+v", i, attachments[0].Fields)
+		}
+	}
+}
+
+func TestOpenTicketSkipsLifecycleChanges(t *testing.T) {
+	s := New(Config{}, NewNullLogger()).(*server)
+
+	hooks := []*Webhook{
+		{Event: SentryEvent{Title: "no issue id"}},
+		{ID: "42", Resource: resourceIssue, Action: issueResolved, Issue: &SentryIssue{}},
+		{ID: "42", Resource: resourceSummary},
+	}
+	for _, hook := range hooks {
+		err := s.openTicket(hook, destination{kind: destinationJira, target: "backend"}, func() (ticket, error) {
+			t.Errorf("no ticket should be opened for %+v", hook)
+			return ticket{}, nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestOpenTicketLocksPerIssue(t *testing.T) {
+	s := New(Config{}, NewNullLogger()).(*server)
+	jira := destination{kind: destinationJira, target: "backend"}
+
+	// the tracker of the first issue hangs until a ticket of another issue was opened
+	started, opened := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- s.openTicket(&Webhook{ID: "1"}, jira, func() (ticket, error) {
+			close(started)
+			<-opened
+			return ticket{Key: "BACK-1"}, nil
+		})
+	}()
+	<-started
+
+	err := s.openTicket(&Webhook{ID: "2"}, jira, func() (ticket, error) {
+		return ticket{Key: "BACK-2"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(opened)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(s.ticketLocks.locks); n != 0 {
+		t.Errorf("expected the locks to be removed, got %d", n)
+	}
+}
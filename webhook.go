@@ -1,7 +1,6 @@
 package slaxy
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -9,7 +8,8 @@ import (
 	"strings"
 )
 
-type webhook struct {
+// Webhook is a normalized sentry webhook of the legacy plugin or the integration platform
+type Webhook struct {
 	ProjectName     string   `json:"project_name"` // "nexus-tracker-test"
 	Message         string   `json:"message"`      // most time this is empty
 	ID              string   `json:"id"`
@@ -19,10 +19,57 @@ type webhook struct {
 	Level           string   `json:"level"`            // "error"
 	TriggeringRules []string `json:"triggering_rules"` // eg: [ "Send a notification for new issues" ]
 
-	Event sentryEvent
+	Event SentryEvent
+
+	// Resource is the integration platform resource, empty for legacy plugin webhooks
+	Resource string `json:"-"`
+	// Action is the integration platform action, eg: "triggered", "resolved"
+	Action string      `json:"-"`
+	Actor  SentryActor `json:"-"`
+
+	// resource specific payloads
+	Issue        *SentryIssue        `json:"-"`
+	Installation *SentryInstallation `json:"-"`
+	MetricAlert  *MetricAlertData    `json:"-"`
+
+	// organization is the slug of the sentry organization of event alerts
+	organization string
+	// signed is set if the payload was verified with a client secret
+	signed bool
 }
 
-type sentryEvent struct {
+// issueKey identifies the sentry issue or metric alert incident the hook belongs to
+func (w *Webhook) issueKey() string {
+	if w.ID == "" {
+		return ""
+	}
+
+	if w.MetricAlert != nil {
+		return "metric_alert/" + w.ID
+	}
+
+	return "issue/" + w.ID
+}
+
+// Title returns the first line of the message or falls back to the event title
+func (w *Webhook) Title() string {
+	// message is empty most of the time
+	if w.Message != "" {
+		lines := strings.Split(w.Message, "\n")
+		if lines[0] != "" {
+			return lines[0]
+		}
+	}
+
+	if w.Event.Location == "" {
+		return w.Event.Title
+	}
+
+	return fmt.Sprintf("[%s] %s", w.Event.Location, w.Event.Title)
+}
+
+// SentryEvent is the event which triggered an alert, issue and metric alert webhooks only carry some of its fields
+type SentryEvent struct {
 	Culprit     string `json:"culprit"`     // the same as parent culprit
 	Title       string `json:"title"`       // "*fmt.wrapError: this is an test error, err=file does not exist"
 	EventID     string `json:"event_id"`    // "fec9f96296cb47d89e652d183e2752cf"
@@ -33,8 +80,8 @@ type sentryEvent struct {
 	Logger      string `json:"logger"`
 	Type        string `json:"type"` // "error"
 
-	Metadata sentryEvtMetadata `json:"metadata"`
-	Tags     []sentryTag
+	Metadata SentryEvtMetadata `json:"metadata"`
+	Tags     []SentryTag
 
 	Timestamp float64 `json:"timestamp"` // 1645672116.893372
 	Received  float64 `json:"received"`  // 1645672117.030224
@@ -44,22 +91,24 @@ type sentryEvent struct {
 	Project int    `json:"project"`
 	Release string `json:"release"` // also event.tags ["sentry:release", "v1.1.0"]
 
-	User      sentryUser `json:"user,omitempty"`
+	User      SentryUser `json:"user,omitempty"`
 	Sdk       Sdk        `json:"sdk"`
 	Exception Exception  `json:"exception"`
 }
 
-type sentryEvtMetadata struct {
+// SentryEvtMetadata is the summary of the exception or message of an event
+type SentryEvtMetadata struct {
 	Function string `json:"function"`
 	Type     string `json:"type"`
 	Value    string `json:"value"`
 	Filename string `json:"filename"`
 }
 
-// sentryTag is an array as two elements, in [key, value] format
-type sentryTag [2]string
+// SentryTag is an array as two elements, in [key, value] format
+type SentryTag [2]string
 
-type sentryUser struct {
+// SentryUser is the user the event happened to
+type SentryUser struct {
 	Username  string `json:"username"`
 	IPAddress string `json:"ip_address"`
 	Geo       struct {
@@ -70,15 +119,18 @@ type sentryUser struct {
 	Email string `json:"email"`
 }
 
+// Sdk is the sentry client library which sent the event
 type Sdk struct {
 	Version string `json:"version"`
 	Name    string `json:"name"`
 }
 
+// Exception holds the chained exceptions of an event, the latest one last
 type Exception struct {
 	Values []ExceptionValue `json:"values"`
 }
 
+// ExceptionValue is one exception of the chain with its stacktrace
 type ExceptionValue struct {
 	Stacktrace Stacktrace `json:"stacktrace"`
 	Type       string     `json:"type"`
@@ -89,10 +141,12 @@ type ExceptionValue struct {
 	} `json:"mechanism"`
 }
 
+// Stacktrace is the stacktrace of an exception
 type Stacktrace struct {
 	Frames []StacktraceFrame `json:"frames"`
 }
 
+// StacktraceFrame is a frame of a stacktrace, sentry sends the innermost frame last
 type StacktraceFrame struct {
 	AbsPath     string        `json:"abs_path"`
 	PreContext  []interface{} `json:"pre_context"`
@@ -100,9 +154,11 @@ type StacktraceFrame struct {
 	InApp       bool          `json:"in_app"`
 	Lineno      int           `json:"lineno"`
 	Filename    string        `json:"filename"`
+	Function    string        `json:"function"`
 	ContextLine string        `json:"context_line"`
 }
 
+// String renders the location and line of the frame in markdown
 func (s *StacktraceFrame) String() string {
 	if s == nil {
 		return ""
@@ -111,6 +167,29 @@ func (s *StacktraceFrame) String() string {
 		s.Filename, s.Lineno, s.AbsPath, s.ContextLine)
 }
 
+// topFrame returns the innermost frame of the latest exception, which is the first one rendered in messages
+func (w *Webhook) topFrame() *StacktraceFrame {
+	frames := w.latestFrames()
+	if len(frames) == 0 {
+		return nil
+	}
+
+	return &frames[len(frames)-1]
+}
+
+// latestFrames returns the frames of the latest exception with a stacktrace, sentry sends it last
+func (w *Webhook) latestFrames() []StacktraceFrame {
+	values := w.Event.Exception.Values
+	for i := len(values) - 1; i >= 0; i-- {
+		if len(values[i].Stacktrace.Frames) > 0 {
+			return values[i].Stacktrace.Frames
+		}
+	}
+
+	return nil
+}
+
+// Request is the http request an event was raised in
 type Request struct {
 	URL                 string                 `json:"url"`
 	Headers             [][]string             `json:"headers"` // "Referer", "Origin"
@@ -128,15 +207,21 @@ func (s *server) handleWebhook(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// the last part is slack channel id
+	// the last part is slack channel id or the name of a discord or teams webhook
 	// /webhook/sentry/:SlackChannelID
-	parts := strings.Split(req.RequestURI, "/")
-	channel := parts[len(parts)-1]
-	if channel == "" {
+	// /webhook/sentry/discord/:DiscordWebhookName
+	// /webhook/sentry/teams/:TeamsWebhookName
+	path := s.parsePath(req.URL.Path)
+	if path.target == "" {
 		w.WriteHeader(400)
 		w.Write([]byte("empty slack channel ID"))
 		return
 	}
+	if err := s.validateDestination(path); err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte(err.Error()))
+		return
+	}
 
 	// read body
 	buf, err := io.ReadAll(req.Body)
@@ -150,19 +235,52 @@ func (s *server) handleWebhook(w http.ResponseWriter, req *http.Request) {
 
 	s.logger.Debugf("read request payload success, body=%s", string(buf))
 
+	// verify the payload was signed by sentry
+	secret := s.secretFor(path)
+	if secret == "" && s.requiresSignature(path) {
+		s.metrics.inc(`slaxy_webhook_rejected_total{reason="missing_secret"}`)
+		s.logger.Warnf("Rejected webhook for %s from %s: no client secret to verify it with", path, req.RemoteAddr)
+		w.WriteHeader(401)
+		w.Write([]byte("no client secret configured"))
+
+		return
+	}
+	if secret != "" && !verifySignature(secret, buf, req.Header.Get(signatureHeader)) {
+		s.metrics.inc(`slaxy_webhook_rejected_total{reason="invalid_signature"}`)
+		s.logger.Warnf("Rejected webhook for %s from %s: invalid %s header", path, req.RemoteAddr, signatureHeader)
+		w.WriteHeader(401)
+		w.Write([]byte("invalid signature"))
+
+		return
+	}
+
 	// parse webhook
-	var hook webhook
+	hook, err := parseWebhook(req.Header.Get(resourceHeader), buf)
+	if errors.Is(err, errUnsupportedResource) {
+		// sentry treats errors as failed deliveries and may disable the integration
+		s.logger.Debugf("Ignored webhook: %s", err)
+		w.WriteHeader(204)
 
-	err = json.Unmarshal(buf, &hook)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(500)
+		w.WriteHeader(400)
 		s.logger.Errorf("Could not parse webhook payload: %s", err.Error())
 
 		return
 	}
 	s.logger.Debugf("parse webhook payload success, payload=%+v", hook)
+	hook.signed = secret != ""
+
+	// installations carry nothing worth posting
+	if hook.Installation != nil {
+		s.logger.Infof("Sentry integration %s %s by %s", hook.Installation.App.Slug, hook.Action, hook.Actor.Name)
+		w.WriteHeader(200)
+
+		return
+	}
 
-	err = errors.Join(s.slackHandleHook(&hook, channel), s.discordHandleHook(&hook))
+	err = s.notify(req.Context(), hook, path)
 	if err != nil {
 		w.WriteHeader(500)
 		s.logger.Errorf("Error while posting message: %s", err.Error())
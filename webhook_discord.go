@@ -1,23 +1,37 @@
 package slaxy
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/innogames/slaxy/version"
 )
 
-func (s *server) discordHandleHook(hook *webhook) error {
-	if s.cfg.DiscordWebhookURL == "" {
-		return nil
+// discord message limits, see https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	discordContentMax     = 2000
+	discordEmbedsMax      = 6000
+	discordTitleMax       = 256
+	discordDescriptionMax = 4096
+	discordFieldsMax      = 25
+	discordFieldNameMax   = 256
+	discordFieldValueMax  = 1024
+	discordFooterMax      = 2048
+)
+
+func (s *server) discordHandleHook(ctx context.Context, hook *Webhook, name string, r *route) error {
+	url, err := s.discordWebhookURL(name)
+	if err != nil || url == "" {
+		return err
 	}
 
-	message := s.createDiscordMessage(hook)
-	res, err := s.client.R().SetBody(message).Post(s.cfg.DiscordWebhookURL)
+	message := s.renderDiscordMessage(ctx, hook, r, s.isFirstAlert(hook, destination{kind: destinationDiscord, target: name}))
+	res, err := s.client.R().SetContext(ctx).SetBody(message).Post(url)
 	if err != nil {
 		message_json, _ := json.Marshal(message)
 		return fmt.Errorf("failed to send discord message, err=%w, message=%v", err, string(message_json))
@@ -30,73 +44,328 @@ func (s *server) discordHandleHook(hook *webhook) error {
 	return nil
 }
 
-// createMessage will create the client message attachment
-func (s *server) createDiscordMessage(hook *webhook) discordgo.MessageSend {
-	buf := bytes.NewBuffer(nil)
-	// default fields
-	fmt.Fprintf(buf, "**Culprit** `%s`\n", hook.Culprit)
-	fmt.Fprintf(buf, "**Project** `%s`\n", hook.ProjectName)
-	fmt.Fprintf(buf, "**Level** `%s`\n", hook.Level)
+// discordWebhookURL returns the url of the named discord webhook, an empty name is the default webhook
+func (s *server) discordWebhookURL(name string) (string, error) {
+	if name == "" {
+		return s.cfg.DiscordWebhookURL, nil
+	}
+
+	if url, ok := lookupFold(s.cfg.DiscordWebhooks, name); ok {
+		return url, nil
+	}
+
+	return "", fmt.Errorf("unknown discord webhook %q", name)
+}
+
+// checkDiscordWebhook checks the connection to the discord webhook
+func (s *server) checkDiscordWebhook(ctx context.Context, url string) error {
+	res, err := s.client.R().SetContext(ctx).Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to check webhook connection err: %w", err)
+	}
+	if res.StatusCode() >= 300 {
+		return fmt.Errorf("failed to get webhook info: %s", res.Body())
+	}
+
+	return nil
+}
+
+// renderDiscordMessage will create the client message with the message template of the route or the built-in layout,
+// shortened to the discord limits, new alerts get the level style of the route and the first alert of an issue
+// its level and owner mentions
+func (s *server) renderDiscordMessage(ctx context.Context, hook *Webhook, r *route, first bool) discordgo.MessageSend {
+	var message discordgo.MessageSend
+	var mentions []string
+	if hook.isNewAlert() {
+		message = s.createDiscordAlertMessage(hook, r)
+		if first {
+			mentions = append(mentions, s.levelStyle(hook, r).discordMention())
+		}
+	} else {
+		message = s.createDiscordMessage(hook, discordFieldValueMax)
+	}
+
+	// mention the owners of the first alert of an issue and of assignments
+	if first {
+		mentions = append(mentions, s.discordMentions(ctx, hook)...)
+	}
+	if mention := joinMentions(mentions...); mention != "" {
+		message.Content = strings.TrimSpace(mention + "\n" + message.Content)
+	}
+
+	return fitDiscordMessage(message)
+}
 
+// createDiscordAlertMessage will create the client message of a new alert in the level style of the route
+func (s *server) createDiscordAlertMessage(hook *Webhook, r *route) discordgo.MessageSend {
+	if !isTemplated(hook, r) {
+		message := s.createDiscordMessage(hook, discordFieldValueMax)
+		style := s.levelStyle(hook, r)
+		for _, embed := range message.Embeds {
+			embed.Title = style.title(embed.Title)
+			embed.Color = colorToInt(style.Color)
+		}
+		return message
+	}
+
+	attachment := s.renderAttachment(hook, r)
+	fields := make([]*discordgo.MessageEmbedField, 0, len(attachment.Fields))
+	for _, field := range attachment.Fields {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   field.Title,
+			Value:  field.Value,
+			Inline: field.Short,
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       attachment.Title,
+		URL:         attachment.TitleLink,
+		Description: attachment.Text,
+		Color:       colorToInt(attachment.Color),
+		Fields:      withoutEmptyFields(fields),
+	}
+	if attachment.Footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: attachment.Footer}
+	}
 	if hook.Event.Timestamp != 0 {
-		fmt.Fprintf(buf, "**Timestamp** `%s`\n", time.Unix(int64(int(hook.Event.Timestamp)), 0).Format(time.RFC3339))
+		embed.Timestamp = time.Unix(int64(hook.Event.Timestamp), 0).UTC().Format(time.RFC3339)
+	}
+
+	return discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}}
+}
+
+// fitDiscordMessage shortens the content and the embeds of the message to the discord limits,
+// inline fields are dropped first if the embeds are too long
+func fitDiscordMessage(message discordgo.MessageSend) discordgo.MessageSend {
+	message.Content = truncateMrkdwn(message.Content, discordContentMax)
+
+	budget := discordEmbedsMax
+	embeds := make([]*discordgo.MessageEmbed, 0, len(message.Embeds))
+	for _, original := range message.Embeds {
+		embed := fitDiscordEmbed(*original)
+		for discordEmbedLength(&embed) > budget && len(embed.Fields) > 0 {
+			embed.Fields = dropDiscordField(embed.Fields)
+		}
+		if over := discordEmbedLength(&embed) - budget; over > 0 {
+			description := ""
+			if keep := utf8.RuneCountInString(embed.Description) - over; keep > 4 {
+				description = truncateMrkdwn(embed.Description, keep)
+			}
+			embed.Description = description
+		}
+
+		budget -= discordEmbedLength(&embed)
+		embeds = append(embeds, &embed)
 	}
+	message.Embeds = embeds
+
+	return message
+}
 
-	if hook.Event.Location != "" {
-		fmt.Fprintf(buf, "**Location** `%s`\n", hook.Event.Location)
+// fitDiscordEmbed shortens the parts of the embed to their limits, a long last field like the stacktrace
+// is kept if there are too many fields
+func fitDiscordEmbed(embed discordgo.MessageEmbed) discordgo.MessageEmbed {
+	embed.Title = truncate(embed.Title, discordTitleMax)
+	embed.Description = truncateMrkdwn(embed.Description, discordDescriptionMax)
+	if embed.Footer != nil {
+		footer := *embed.Footer
+		footer.Text = truncate(footer.Text, discordFooterMax)
+		embed.Footer = &footer
 	}
 
-	if hook.Event.Environment != "" {
-		fmt.Fprintf(buf, "**Environment** `%s`\n", hook.Event.Environment)
+	fields := embed.Fields
+	if len(fields) > discordFieldsMax {
+		last := fields[len(fields)-1]
+		fields = fields[:discordFieldsMax]
+		if !last.Inline {
+			fields[discordFieldsMax-1] = last
+		}
 	}
 
-	if hook.Event.Release != "" {
-		fmt.Fprintf(buf, "**Release** `%s`\n", hook.Event.Release)
+	embed.Fields = make([]*discordgo.MessageEmbedField, 0, len(fields))
+	for _, field := range fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   truncate(field.Name, discordFieldNameMax),
+			Value:  truncateMrkdwn(field.Value, discordFieldValueMax),
+			Inline: field.Inline,
+		})
 	}
 
-	// put all sentry tags as attachment fields
-	for _, tag := range hook.Event.Tags {
-		tagKey := tag[0]
-		tagValue := tag[1]
-		// skip the default fields we already set
-		if tagKey == "culprit" || tagKey == "project" || tagKey == "level" ||
-			tagKey == "location" || tagKey == "environment" || tagKey == "release" || tagKey == "sentry:release" {
-			continue
+	return embed
+}
+
+// dropDiscordField removes the last inline field or the last field if none is inline
+func dropDiscordField(fields []*discordgo.MessageEmbedField) []*discordgo.MessageEmbedField {
+	drop := len(fields) - 1
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Inline {
+			drop = i
+			break
 		}
+	}
 
-		// skip everything that is user-excluded
-		if s.isExcluded(tagKey) {
-			continue
+	return append(fields[:drop:drop], fields[drop+1:]...)
+}
+
+// discordEmbedLength counts the characters of the embed towards the limit of all embeds of a message
+func discordEmbedLength(embed *discordgo.MessageEmbed) int {
+	n := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	if embed.Footer != nil {
+		n += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		n += utf8.RuneCountInString(embed.Author.Name)
+	}
+	for _, field := range embed.Fields {
+		n += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+
+	return n
+}
+
+// createDiscordMessage will create the client message, the stacktrace is rendered in at most stacktraceMax characters
+func (s *server) createDiscordMessage(hook *Webhook, stacktraceMax int) discordgo.MessageSend {
+	if hook.Resource == resourceSummary {
+		return discordgo.MessageSend{Content: hook.Message}
+	}
+	if hook.MetricAlert != nil {
+		return s.createDiscordMetricAlertMessage(hook)
+	}
+	if hook.isStatusChange() {
+		return s.createDiscordStatusChangeMessage(hook)
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Project", Value: hook.ProjectName, Inline: true},
+		{Name: "Level", Value: hook.level(), Inline: true},
+		{Name: "Environment", Value: hook.environment(), Inline: true},
+		{Name: "Release", Value: hook.release(), Inline: true},
+		{Name: "Location", Value: hook.Event.Location, Inline: true},
+	}
+
+	// put all sentry tags as inline fields
+	for _, field := range s.tagFields(hook) {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: field.Title, Value: field.Value, Inline: true})
+	}
+
+	// the stacktrace goes last, in code blocks
+	if stacktrace := s.renderStacktrace(hook, markdownStacktrace, stacktraceMax); stacktrace != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  "Stacktrace",
+			Value: stacktrace,
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:  hook.Title(),
+		URL:    hook.URL,
+		Color:  colorToInt(s.levelStyle(hook, nil).Color),
+		Fields: withoutEmptyFields(fields),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("sentry-alert v%v", version.Version),
+		},
+	}
+	if hook.Culprit != "" {
+		embed.Description = "`" + hook.Culprit + "`"
+	}
+	if hook.Event.Timestamp != 0 {
+		embed.Timestamp = time.Unix(int64(hook.Event.Timestamp), 0).UTC().Format(time.RFC3339)
+	}
+
+	return discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}}
+}
+
+// withoutEmptyFields removes the fields without a value, discord rejects them
+func withoutEmptyFields(fields []*discordgo.MessageEmbedField) []*discordgo.MessageEmbedField {
+	filtered := fields[:0]
+	for _, field := range fields {
+		if strings.TrimSpace(field.Value) != "" {
+			filtered = append(filtered, field)
 		}
+	}
+
+	return filtered
+}
+
+// createDiscordMetricAlertMessage will create the client message of a metric alert
+func (s *server) createDiscordMetricAlertMessage(hook *Webhook) discordgo.MessageSend {
+	alert := hook.MetricAlert
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   "Alert Rule",
+			Value:  alert.MetricAlert.AlertRule.Name,
+			Inline: true,
+		},
+		{
+			Name:   "State",
+			Value:  alert.state(hook.Action),
+			Inline: true,
+		},
+	}
 
-		title := strings.Title(strings.ReplaceAll(tagKey, "_", " "))
-		fmt.Fprintf(buf, "**%s**: `%s`\n", title, tagValue)
+	if threshold := alert.threshold(hook.Action); threshold != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "Threshold",
+			Value:  threshold,
+			Inline: true,
+		})
 	}
 
-	var title string
-	// message is empty most of the time
-	if hook.Message != "" {
-		lines := strings.Split(hook.Message, "\n")
-		title = lines[0]
+	if value := alert.currentValue(); value != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "Current Value",
+			Value:  value,
+			Inline: true,
+		})
 	}
 
-	if title == "" {
-		// fallback to event.title
-		title = fmt.Sprintf("[%s] %s", hook.Event.Location, hook.Event.Title)
+	if hook.ProjectName != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "Project",
+			Value:  hook.ProjectName,
+			Inline: true,
+		})
 	}
 
-	if len(hook.Event.Exception.Values) > 0 && len(hook.Event.Exception.Values[0].Stacktrace.Frames) > 0 {
-		frameLen := len(hook.Event.Exception.Values[0].Stacktrace.Frames)
-		buf.WriteString("### Stacktrace\n")
-		buf.WriteString(hook.Event.Exception.Values[0].Stacktrace.Frames[frameLen-1].String())
+	if hook.Event.Environment != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "Environment",
+			Value:  hook.Event.Environment,
+			Inline: true,
+		})
+	}
+
+	return discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       hook.Title(),
+				URL:         hook.URL,
+				Description: alert.details(),
+				Color:       colorToInt(alert.color(hook.Action)),
+				Fields:      fields,
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: fmt.Sprintf("sentry-alert v%v", version.Version),
+				},
+			},
+		},
+	}
+}
+
+// createDiscordStatusChangeMessage will create a compact client message of an issue lifecycle change
+func (s *server) createDiscordStatusChangeMessage(hook *Webhook) discordgo.MessageSend {
+	title := hook.Title()
+	if hook.Issue.ShortID != "" {
+		title = hook.Issue.ShortID + ": " + title
 	}
 
 	return discordgo.MessageSend{
-		Content: title + "\n" + buf.String(),
 		Embeds: []*discordgo.MessageEmbed{
 			{
-				Title:       fmt.Sprintf("sentry-alert v%v", version.Version),
-				Description: hook.URL,
+				Title:       title,
+				URL:         hook.URL,
+				Description: hook.statusChange(),
+				Color:       colorToInt(hook.statusColor()),
 			},
 		},
 	}
@@ -1,8 +1,13 @@
 package slaxy
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,7 +15,7 @@ import (
 )
 
 func TestDiscordPostMessage(t *testing.T) {
-	hook := webhook{
+	hook := Webhook{
 		ProjectName:     "demo-project",
 		Message:         "",
 		ID:              "007",
@@ -19,7 +24,7 @@ func TestDiscordPostMessage(t *testing.T) {
 		URL:             "https://www.google.com/",
 		Level:           "error",
 		TriggeringRules: nil,
-		Event: sentryEvent{
+		Event: SentryEvent{
 			Culprit:     "createMessage()",
 			Title:       "<this is 'title'>",
 			EventID:     "",
@@ -29,8 +34,8 @@ func TestDiscordPostMessage(t *testing.T) {
 			Location:    "webhook.go",
 			Logger:      "",
 			Type:        "",
-			Metadata:    sentryEvtMetadata{},
-			Tags: []sentryTag{
+			Metadata:    SentryEvtMetadata{},
+			Tags: []SentryTag{
 				{"key", "value"},
 				{"key", "value"},
 				{"key", "value"},
@@ -61,7 +66,7 @@ func TestDiscordPostMessage(t *testing.T) {
 			Level:     "error",
 			Project:   0,
 			Release:   "0.2.9",
-			User:      sentryUser{},
+			User:      SentryUser{},
 			Sdk: Sdk{
 				Version: "0.12.0",
 				Name:    "sentry-go",
@@ -115,7 +120,7 @@ func TestDiscordPostMessage(t *testing.T) {
 	s.setup(":8080", func(l net.Listener) {
 
 	})
-	attachment := s.createDiscordMessage(&hook)
+	attachment := s.createDiscordMessage(&hook, discordFieldValueMax)
 	res, err := s.client.R().SetBody(attachment).Post(s.cfg.DiscordWebhookURL)
 	if err != nil {
 		t.Fatal(err)
@@ -125,3 +130,130 @@ func TestDiscordPostMessage(t *testing.T) {
 	}
 	t.Logf("success")
 }
+
+func TestDiscordNamedWebhooks(t *testing.T) {
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			received <- r.URL.Path
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := New(Config{
+		DiscordWebhookURL: srv.URL + "/default",
+		DiscordWebhooks:   map[string]string{"ops": srv.URL + "/ops"},
+	}, NewNullLogger()).(*server)
+	if err := setupTestServer(t, s); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"project_name": "backend", "culprit": "main.run", "level": "error", "event": {"title": "boom"}}`
+	for path, want := range map[string]string{
+		"/webhook/sentry/discord/OPS": "/ops",
+		"/webhook/sentry/C0123":       "/default",
+	} {
+		rec := httptest.NewRecorder()
+		s.handleWebhook(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d: %s", path, rec.Code, rec.Body.String())
+		}
+		if got := <-received; got != want {
+			t.Errorf("%s: expected post to %s, got %s", path, want, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleWebhook(rec, httptest.NewRequest(http.MethodPost, "/webhook/sentry/discord/unknown", strings.NewReader(body)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown webhook, got %d", rec.Code)
+	}
+}
+
+func TestDiscordEmbed(t *testing.T) {
+	s := New(Config{}, NewNullLogger()).(*server)
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Culprit:     "main.run",
+		URL:         "https://sentry.io/organizations/acme/issues/1/",
+		Level:       "warning",
+		Event: SentryEvent{
+			Title:       "index out of range",
+			Environment: "production",
+			Timestamp:   1645672116.893372,
+			Tags:        []SentryTag{{"server_name", "web-1"}},
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+			}}}}},
+		},
+	}
+
+	message := s.renderDiscordMessage(context.Background(), hook, nil, true)
+	if message.Content != "" || len(message.Embeds) != 1 {
+		t.Fatalf("expected a single embed, got %+v", message)
+	}
+
+	embed := message.Embeds[0]
+	if embed.Title != "index out of range" || embed.URL != hook.URL || embed.Description != "`main.run`" {
+		t.Errorf("unexpected embed %+v", embed)
+	}
+	if embed.Color != colorToInt(colorWarning) || embed.Timestamp != "2022-02-24T03:08:36Z" {
+		t.Errorf("unexpected color %x or timestamp %s", embed.Color, embed.Timestamp)
+	}
+
+	var names []string
+	for _, field := range embed.Fields {
+		names = append(names, field.Name)
+	}
+	if got := strings.Join(names, ","); got != "Project,Level,Environment,Server Name,Stacktrace" {
+		t.Errorf("unexpected fields %s", got)
+	}
+	if stacktrace := embed.Fields[len(embed.Fields)-1]; stacktrace.Inline || stacktrace.Value != "`main.go:42`\n```\npanic(err)\n```" {
+		t.Errorf("unexpected stacktrace %+v", stacktrace)
+	}
+}
+
+func TestDiscordEmbedLimits(t *testing.T) {
+	s := New(Config{}, NewNullLogger()).(*server)
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Level:       "fatal",
+		Event: SentryEvent{
+			Title: strings.Repeat("t", 300),
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: strings.Repeat("x\n", 1000)},
+			}}}}},
+		},
+	}
+	for i := 0; i < 40; i++ {
+		hook.Event.Tags = append(hook.Event.Tags, SentryTag{fmt.Sprintf("tag_%d", i), strings.Repeat("v", 300)})
+	}
+
+	embed := s.renderDiscordMessage(context.Background(), hook, nil, true).Embeds[0]
+	if embed.Color != colorToInt(colorFatal) {
+		t.Errorf("unexpected color %x", embed.Color)
+	}
+	if n := len([]rune(embed.Title)); n != discordTitleMax {
+		t.Errorf("expected the title to be cut to %d characters, got %d", discordTitleMax, n)
+	}
+	if len(embed.Fields) > discordFieldsMax {
+		t.Errorf("expected at most %d fields, got %d", discordFieldsMax, len(embed.Fields))
+	}
+	if n := discordEmbedLength(embed); n > discordEmbedsMax {
+		t.Errorf("expected at most %d characters, got %d", discordEmbedsMax, n)
+	}
+
+	// the tags are dropped before the stacktrace
+	stacktrace := embed.Fields[len(embed.Fields)-1]
+	if stacktrace.Name != "Stacktrace" || len([]rune(stacktrace.Value)) > discordFieldValueMax || !strings.HasSuffix(stacktrace.Value, "\n```") {
+		t.Errorf("unexpected stacktrace field %q", stacktrace.Value)
+	}
+
+	summary := s.renderDiscordMessage(context.Background(), &Webhook{Resource: resourceSummary, Message: strings.Repeat("s", 3000)}, nil, true)
+	if n := len([]rune(summary.Content)); n > discordContentMax {
+		t.Errorf("expected the content to be cut to %d characters, got %d", discordContentMax, n)
+	}
+}
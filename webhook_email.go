@@ -0,0 +1,434 @@
+package slaxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innogames/slaxy/version"
+)
+
+// SMTPConfig is the mail server emails are sent with
+type SMTPConfig struct {
+	// Addr is the host and port of the mail server, eg: "smtp.example.com:587", STARTTLS is used if offered
+	Addr     string `mapstructure:"addr"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+	// AllowAddresses accepts any "email:<address>" destination besides the email destinations,
+	// addresses of webhook paths are only accepted from requests signed with a client secret
+	AllowAddresses bool `mapstructure:"allow-addresses"`
+}
+
+// smtpTimeout is how long sending an email may take
+const smtpTimeout = 30 * time.Second
+
+// EmailDestination sends alerts to its recipients, selected by "email:<name>" destinations
+// or the webhook path /webhook/sentry/email/<name>
+type EmailDestination struct {
+	To []string `mapstructure:"to"`
+	// Digest collects the alerts and sends them "hourly", "daily" or in any other interval like "4h",
+	// every alert is sent immediately if empty
+	Digest string `mapstructure:"digest"`
+}
+
+// emailDestination is a compiled EmailDestination with its pending digest
+type emailDestination struct {
+	name     string
+	to       []string
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []*Webhook
+}
+
+// compileEmailDestinations parses the digest intervals of the email destinations
+func compileEmailDestinations(cfg map[string]EmailDestination) (map[string]*emailDestination, error) {
+	destinations := make(map[string]*emailDestination, len(cfg))
+	for name, d := range cfg {
+		if len(d.To) == 0 {
+			return nil, fmt.Errorf("email destination %s has no recipients", name)
+		}
+
+		var interval time.Duration
+		switch d.Digest {
+		case "":
+		case "hourly":
+			interval = time.Hour
+		case "daily":
+			interval = 24 * time.Hour
+		default:
+			var err error
+			interval, err = time.ParseDuration(d.Digest)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("invalid digest interval %q of email destination %s", d.Digest, name)
+			}
+		}
+
+		// viper lowercases map keys
+		name = strings.ToLower(name)
+		destinations[name] = &emailDestination{name: name, to: d.To, interval: interval}
+	}
+
+	return destinations, nil
+}
+
+func (s *server) emailHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	d, err := s.emailDestination(name)
+	if err != nil {
+		return err
+	}
+
+	if d.interval > 0 {
+		d.mu.Lock()
+		d.pending = append(d.pending, hook)
+		d.mu.Unlock()
+
+		return nil
+	}
+
+	body, err := s.renderEmail(hook)
+	if err != nil {
+		return err
+	}
+
+	subject := hook.Title()
+	if hook.ProjectName != "" {
+		subject = fmt.Sprintf("[%s] %s", hook.ProjectName, subject)
+	}
+
+	return s.sendEmail(ctx, d.to, subject, body)
+}
+
+// emailDestination returns the named email destination, allowed addresses are immediate destinations of their own
+func (s *server) emailDestination(name string) (*emailDestination, error) {
+	if d, ok := s.emailDestinations[strings.ToLower(name)]; ok {
+		return d, nil
+	}
+	if isEmailAddress(name) {
+		if !s.cfg.SMTP.AllowAddresses {
+			return nil, fmt.Errorf("email address %q is no email destination, addresses are not allowed", name)
+		}
+		return &emailDestination{name: name, to: []string{name}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown email destination %q", name)
+}
+
+// isEmailAddress reports whether the target of an email destination is an address rather than a name
+func isEmailAddress(target string) bool {
+	return strings.Contains(target, "@")
+}
+
+// requiresSignature reports whether the webhook path must be signed, the email addresses of paths
+// would relay mail to anyone otherwise
+func (s *server) requiresSignature(path destination) bool {
+	if path.kind != destinationEmail || !isEmailAddress(path.target) {
+		return false
+	}
+	_, named := s.emailDestinations[strings.ToLower(path.target)]
+
+	return !named
+}
+
+// sendDigests sends the digest of the email destination every interval until the server is stopped,
+// the pending alerts are sent on stop which waits for them
+func (s *server) sendDigests(d *emailDestination) {
+	defer s.digests.Done()
+
+	for {
+		// align the digests to full hours and days
+		next := time.Now().Truncate(d.interval).Add(d.interval)
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-s.done:
+			timer.Stop()
+			if err := s.sendDigest(d); err != nil {
+				s.logger.Errorf("failed to send email digest to %s: %s", d.name, err)
+			}
+			return
+		case <-timer.C:
+			if err := s.sendDigest(d); err != nil {
+				s.logger.Errorf("failed to send email digest to %s: %s", d.name, err)
+			}
+		}
+	}
+}
+
+// sendDigest sends the pending alerts of the email destination, nothing is sent without alerts
+func (s *server) sendDigest(d *emailDestination) error {
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = nil
+	d.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	body, err := s.renderDigest(pending)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Sentry digest: %d alerts", len(pending))
+	if len(pending) == 1 {
+		subject = "Sentry digest: 1 alert"
+	}
+
+	return s.sendEmail(context.Background(), d.to, subject, body)
+}
+
+// sendEmail sends the html body to the recipients
+func (s *server) sendEmail(ctx context.Context, to []string, subject string, body []byte) error {
+	from := s.cfg.SMTP.From
+	if from == "" {
+		from = "slaxy@localhost"
+	}
+
+	msg := bytes.NewBuffer(nil)
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(msg)
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if err := s.sendSMTP(ctx, from, to, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email, err=%w", err)
+	}
+
+	return nil
+}
+
+// sendSMTP sends the message like smtp.SendMail, but gives up once the context is done or the smtp timeout passed
+func (s *server) sendSMTP(ctx context.Context, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.cfg.SMTP.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// a stalled mail server fails the reads and writes at the deadline or once the context is canceled
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(s.cfg.SMTP.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.SMTP.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.SMTP.Username, s.cfg.SMTP.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// emailAlert is an alert of an email, the fields are the ones of the slack attachment
+type emailAlert struct {
+	Title  string
+	URL    string
+	Text   string
+	Color  string
+	Short  [][2]string
+	Long   [][2]string
+	Count  int
+	Footer string
+}
+
+// newEmailAlert will create the email alert of the same fields as the slack attachment
+func (s *server) newEmailAlert(hook *Webhook) *emailAlert {
+	attachment := s.createAttachment(hook)
+
+	alert := &emailAlert{
+		Title:  attachment.Title,
+		URL:    attachment.TitleLink,
+		Text:   attachment.Text,
+		Color:  attachment.Color,
+		Count:  1,
+		Footer: "Slaxy v" + version.Version,
+	}
+	if alert.Title == "" {
+		alert.Title = hook.Title()
+	}
+
+	for _, field := range attachment.Fields {
+		if field.Value == "" {
+			continue
+		}
+		if field.Short {
+			alert.Short = append(alert.Short, [2]string{field.Title, field.Value})
+			continue
+		}
+		alert.Long = append(alert.Long, [2]string{field.Title, field.Value})
+	}
+
+	return alert
+}
+
+// emailDigestGroup are the alerts of a project and environment
+type emailDigestGroup struct {
+	Project     string
+	Environment string
+	Alerts      []*emailAlert
+}
+
+var emailTemplate = template.Must(template.New("email").Parse(`
+{{- define "alert" -}}
+<table style="border-left: 4px solid {{ .Color }}; padding-left: 8px; margin-bottom: 16px; font-family: sans-serif;">
+<tr><td colspan="2"><a href="{{ .URL }}" style="font-weight: bold; font-size: 16px;">{{ .Title }}</a>
+{{- if gt .Count 1 }} <span style="color: #666;">({{ .Count }} times)</span>{{ end }}</td></tr>
+{{- if .Text }}
+<tr><td colspan="2" style="white-space: pre-wrap;">{{ .Text }}</td></tr>
+{{- end }}
+{{- range .Short }}
+<tr><td style="font-weight: bold; padding-right: 12px;">{{ index . 0 }}</td><td>{{ index . 1 }}</td></tr>
+{{- end }}
+{{- range .Long }}
+<tr><td colspan="2" style="font-weight: bold;">{{ index . 0 }}</td></tr>
+<tr><td colspan="2"><pre style="background: #f6f6f6; padding: 8px;">{{ index . 1 }}</pre></td></tr>
+{{- end }}
+</table>
+{{- end -}}
+
+{{- define "immediate" -}}
+<html><body>
+{{ template "alert" . }}
+<p style="color: #999; font-size: 12px;">{{ .Footer }}</p>
+</body></html>
+{{- end -}}
+
+{{- define "digest" -}}
+<html><body style="font-family: sans-serif;">
+{{- range .Groups }}
+<h2>{{ .Project }}{{ if .Environment }} / {{ .Environment }}{{ end }}</h2>
+{{- range .Alerts }}
+{{ template "alert" . }}
+{{- end }}
+{{- end }}
+<p style="color: #999; font-size: 12px;">{{ .Footer }}</p>
+</body></html>
+{{- end -}}
+`))
+
+// renderEmail renders the html email of the hook
+func (s *server) renderEmail(hook *Webhook) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := emailTemplate.ExecuteTemplate(buf, "immediate", s.newEmailAlert(hook)); err != nil {
+		return nil, fmt.Errorf("failed to render email, err: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// renderDigest renders the html digest of the hooks grouped by project and environment,
+// repeated alerts of an issue are counted
+func (s *server) renderDigest(hooks []*Webhook) ([]byte, error) {
+	groups := map[[2]string]*emailDigestGroup{}
+	byIssue := map[[3]string]*emailAlert{}
+	for _, hook := range hooks {
+		groupKey := [2]string{hook.ProjectName, hook.environment()}
+		group, ok := groups[groupKey]
+		if !ok {
+			group = &emailDigestGroup{Project: groupKey[0], Environment: groupKey[1]}
+			groups[groupKey] = group
+		}
+
+		// lifecycle changes are listed on their own
+		repeatable := hook.issueKey() != "" && !hook.isStatusChange()
+		issueKey := [3]string{groupKey[0], groupKey[1], hook.issueKey()}
+		if alert, ok := byIssue[issueKey]; ok && repeatable {
+			alert.Count++
+			continue
+		}
+
+		alert := s.newEmailAlert(hook)
+		group.Alerts = append(group.Alerts, alert)
+		if repeatable {
+			byIssue[issueKey] = alert
+		}
+	}
+
+	sorted := make([]*emailDigestGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Project != sorted[j].Project {
+			return sorted[i].Project < sorted[j].Project
+		}
+		return sorted[i].Environment < sorted[j].Environment
+	})
+
+	data := struct {
+		Groups []*emailDigestGroup
+		Footer string
+	}{sorted, "Slaxy v" + version.Version}
+
+	buf := bytes.NewBuffer(nil)
+	if err := emailTemplate.ExecuteTemplate(buf, "digest", data); err != nil {
+		return nil, fmt.Errorf("failed to render email digest, err: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
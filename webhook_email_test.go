@@ -0,0 +1,326 @@
+package slaxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a local smtp stand-in which keeps the received messages
+type fakeSMTP struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []*mail.Message
+	rcpts    [][]string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeSMTP{t: t, listener: l}
+	go f.serve()
+	t.Cleanup(func() { l.Close() })
+
+	return f
+}
+
+func (f *fakeSMTP) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+
+	c := textproto.NewConn(conn)
+	_ = c.PrintfLine("220 localhost ESMTP")
+
+	var rcpts []string
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "RCPT":
+			rcpts = append(rcpts, strings.Trim(strings.TrimPrefix(line[len("RCPT TO:"):], " "), "<>"))
+			_ = c.PrintfLine("250 OK")
+		case "DATA":
+			_ = c.PrintfLine("354 go ahead")
+			msg, err := mail.ReadMessage(c.DotReader())
+			if err != nil {
+				f.t.Error(err)
+				return
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, msg)
+			f.rcpts = append(f.rcpts, rcpts)
+			f.mu.Unlock()
+			rcpts = nil
+			_ = c.PrintfLine("250 OK")
+		case "QUIT":
+			_ = c.PrintfLine("221 bye")
+			return
+		default:
+			_ = c.PrintfLine("250 OK")
+		}
+	}
+}
+
+// received returns the subjects and decoded bodies of the received messages
+func (f *fakeSMTP) received() ([]string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var subjects, bodies []string
+	for _, msg := range f.messages {
+		subjects = append(subjects, msg.Header.Get("Subject"))
+		body, err := io.ReadAll(quotedprintable.NewReader(bufio.NewReader(msg.Body)))
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		bodies = append(bodies, string(body))
+	}
+
+	return subjects, bodies
+}
+
+func TestEmailImmediate(t *testing.T) {
+	smtp := newFakeSMTP(t)
+
+	s := New(Config{SMTP: SMTPConfig{Addr: smtp.addr(), From: "sentry@example.com"}}, NewNullLogger()).(*server)
+	var err error
+	s.emailDestinations, err = compileEmailDestinations(map[string]EmailDestination{
+		"Backend": {To: []string{"dev@example.com", "ops@example.com"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Culprit:     "main.run",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/1/",
+		Event: SentryEvent{
+			Title:       "<script> boom",
+			Environment: "production",
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+			}}}}},
+		},
+	}
+	if err := s.emailHandleHook(context.Background(), hook, "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	subjects, bodies := smtp.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected one email, got %d", len(bodies))
+	}
+	if subjects[0] != "[backend] <script> boom" {
+		t.Errorf("unexpected subject %q", subjects[0])
+	}
+	if len(smtp.rcpts[0]) != 2 {
+		t.Errorf("unexpected recipients %v", smtp.rcpts[0])
+	}
+	for _, expected := range []string{"&lt;script&gt; boom", "production", "<pre", "panic(err)", hook.URL} {
+		if !strings.Contains(bodies[0], expected) {
+			t.Errorf("body does not contain %q: %s", expected, bodies[0])
+		}
+	}
+
+	// addresses are destinations of their own once allowed
+	if err := s.emailHandleHook(context.Background(), hook, "jane@example.com"); err == nil {
+		t.Error("expected an error for an address which is not allowed")
+	}
+	s.cfg.SMTP.AllowAddresses = true
+	if err := s.emailHandleHook(context.Background(), hook, "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, bodies := smtp.received(); len(bodies) != 2 {
+		t.Errorf("expected a second email, got %d", len(bodies))
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	smtp := newFakeSMTP(t)
+
+	s := New(Config{SMTP: SMTPConfig{Addr: smtp.addr()}}, NewNullLogger()).(*server)
+	var err error
+	s.emailDestinations, err = compileEmailDestinations(map[string]EmailDestination{
+		"managers": {To: []string{"boss@example.com"}, Digest: "daily"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := []*Webhook{
+		{ID: "1", ProjectName: "backend", Event: SentryEvent{Title: "boom", Environment: "production"}},
+		{ID: "1", ProjectName: "backend", Event: SentryEvent{Title: "boom", Environment: "production"}},
+		{ID: "2", ProjectName: "backend", Event: SentryEvent{Title: "bang", Environment: "staging"}},
+		{ID: "3", ProjectName: "api", Event: SentryEvent{Title: "crash", Environment: "production"}},
+	}
+	for _, hook := range hooks {
+		if err := s.emailHandleHook(context.Background(), hook, "managers"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, bodies := smtp.received(); len(bodies) != 0 {
+		t.Fatalf("digest alerts should not be sent immediately, got %d", len(bodies))
+	}
+
+	d, _ := s.emailDestination("managers")
+	if err := s.sendDigest(d); err != nil {
+		t.Fatal(err)
+	}
+	// nothing is pending anymore
+	if err := s.sendDigest(d); err != nil {
+		t.Fatal(err)
+	}
+
+	subjects, bodies := smtp.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected one digest, got %d", len(bodies))
+	}
+	if subjects[0] != "Sentry digest: 4 alerts" {
+		t.Errorf("unexpected subject %q", subjects[0])
+	}
+
+	body := bodies[0]
+	api := strings.Index(body, "<h2>api / production</h2>")
+	prod := strings.Index(body, "<h2>backend / production</h2>")
+	staging := strings.Index(body, "<h2>backend / staging</h2>")
+	if api < 0 || prod < api || staging < prod {
+		t.Errorf("groups are missing or not sorted: %s", body)
+	}
+	if !strings.Contains(body, "(2 times)") || strings.Count(body, ">boom</a>") != 1 {
+		t.Errorf("repeated alerts should be counted: %s", body)
+	}
+}
+
+func TestStopSendsPendingDigests(t *testing.T) {
+	smtp := newFakeSMTP(t)
+
+	s := New(Config{
+		GracePeriod:       time.Minute,
+		SMTP:              SMTPConfig{Addr: smtp.addr()},
+		EmailDestinations: map[string]EmailDestination{"managers": {To: []string{"boss@example.com"}, Digest: "daily"}},
+	}, NewNullLogger()).(*server)
+	if err := setupTestServer(t, s); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := &Webhook{ID: "1", ProjectName: "backend", Event: SentryEvent{Title: "boom"}}
+	if err := s.emailHandleHook(context.Background(), hook, "managers"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the process exits right after stop returns
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if subjects, _ := smtp.received(); len(subjects) != 1 || subjects[0] != "Sentry digest: 1 alert" {
+		t.Errorf("expected the pending digest to be sent on stop, got %v", subjects)
+	}
+}
+
+func TestEmailAddressPathRequiresSecret(t *testing.T) {
+	smtp := newFakeSMTP(t)
+
+	s := New(Config{SMTP: SMTPConfig{Addr: smtp.addr(), AllowAddresses: true}}, NewNullLogger()).(*server)
+	if err := setupTestServer(t, s); err != nil {
+		t.Fatal(err)
+	}
+
+	// anyone could relay mail to any address through an unsigned path
+	body := `{"project_name": "backend", "event": {"title": "boom"}}`
+	rec := httptest.NewRecorder()
+	s.handleWebhook(rec, httptest.NewRequest(http.MethodPost, "/webhook/sentry/email/victim@example.com", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+	if _, bodies := smtp.received(); len(bodies) != 0 {
+		t.Errorf("no email should be sent, got %d", len(bodies))
+	}
+
+	s.cfg.ClientSecret = "secret"
+	req := httptest.NewRequest(http.MethodPost, "/webhook/sentry/email/jane@example.com", strings.NewReader(body))
+	req.Header.Set(signatureHeader, sign("secret", body))
+	rec = httptest.NewRecorder()
+	s.handleWebhook(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestSendEmailStalledServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		// accept without ever greeting
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	s := New(Config{SMTP: SMTPConfig{Addr: l.Addr().String()}}, NewNullLogger()).(*server)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.sendEmail(ctx, []string{"dev@example.com"}, "boom", []byte("boom")) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected an error for a stalled server")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sending did not give up")
+	}
+}
+
+func TestCompileEmailDestinationsErrors(t *testing.T) {
+	tests := map[string]EmailDestination{
+		"no recipients":    {Digest: "daily"},
+		"invalid interval": {To: []string{"dev@example.com"}, Digest: "weekly"},
+	}
+
+	for name, d := range tests {
+		if _, err := compileEmailDestinations(map[string]EmailDestination{"test": d}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
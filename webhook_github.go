@@ -0,0 +1,98 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// githubAPIURL is the default github api, use https://<host>/api/v3 for github enterprise
+const githubAPIURL = "https://api.github.com"
+
+// GitHubRepo opens github issues for alerts, selected by "github:<name>" destinations
+type GitHubRepo struct {
+	// Repo is the owner and name of the repository, eg: "acme/backend"
+	Repo string `mapstructure:"repo"`
+	// Token is a token which may create issues in the repository
+	Token  string   `mapstructure:"token"`
+	Labels []string `mapstructure:"labels"`
+	// APIURL defaults to https://api.github.com
+	APIURL string `mapstructure:"api-url"`
+}
+
+// githubIssue is the body of the create issue request of the github rest api
+type githubIssue struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// githubCreatedIssue is the response of the create issue request
+type githubCreatedIssue struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// githubError is the error response of the github rest api
+type githubError struct {
+	Message string `json:"message"`
+}
+
+func (s *server) githubHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	repo, err := s.githubRepo(name)
+	if err != nil {
+		return err
+	}
+
+	return s.openTicket(hook, destination{kind: destinationGitHub, target: name}, func() (ticket, error) {
+		apiURL := strings.TrimSuffix(repo.APIURL, "/")
+		if apiURL == "" {
+			apiURL = githubAPIURL
+		}
+
+		var created githubCreatedIssue
+		var githubErr githubError
+		res, err := s.client.R().
+			SetContext(ctx).
+			SetAuthToken(repo.Token).
+			SetHeader("Accept", "application/vnd.github+json").
+			SetBody(s.createGitHubIssue(hook, repo)).
+			SetResult(&created).
+			SetError(&githubErr).
+			Post(fmt.Sprintf("%s/repos/%s/issues", apiURL, repo.Repo))
+		if err != nil {
+			return ticket{}, fmt.Errorf("failed to create github issue, err=%w", err)
+		}
+		if res.StatusCode() >= 300 {
+			return ticket{}, fmt.Errorf("failed to create github issue, status=%d, message=%s", res.StatusCode(), githubErr.Message)
+		}
+
+		return ticket{
+			Key: fmt.Sprintf("%s#%d", repo.Repo, created.Number),
+			URL: created.HTMLURL,
+		}, nil
+	})
+}
+
+// githubRepo returns the named github repository
+func (s *server) githubRepo(name string) (GitHubRepo, error) {
+	if repo, ok := lookupFold(s.cfg.GitHubRepos, name); ok {
+		return repo, nil
+	}
+
+	return GitHubRepo{}, fmt.Errorf("unknown github repo %q", name)
+}
+
+// createGitHubIssue will create the github issue of the hook, the body is markdown
+func (s *server) createGitHubIssue(hook *Webhook, repo GitHubRepo) githubIssue {
+	body := ticketDescription(hook)
+	if stacktrace := s.renderStacktrace(hook, markdownStacktrace, ticketStacktraceMax); stacktrace != "" {
+		body += "\n\nStacktrace:\n" + stacktrace
+	}
+
+	return githubIssue{
+		Title:  truncate(strings.ReplaceAll(hook.Title(), "\n", " "), 256),
+		Body:   body,
+		Labels: repo.Labels,
+	}
+}
@@ -0,0 +1,54 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitHubHandleHook(t *testing.T) {
+	var issue githubIssue
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/backend/issues" || r.Header.Get("Authorization") != "Bearer ghp_test" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&issue); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number": 12, "html_url": "https://github.com/acme/backend/issues/12"}`))
+	}))
+	defer srv.Close()
+
+	s := New(Config{
+		GitHubRepos: map[string]GitHubRepo{"backend": {Repo: "acme/backend", Token: "ghp_test", APIURL: srv.URL}},
+	}, NewNullLogger()).(*server)
+
+	hook := &Webhook{
+		ID:      "42",
+		Culprit: "main.run",
+		URL:     "https://sentry.io/organizations/acme/issues/42/",
+		Event: SentryEvent{
+			Title: "boom",
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+			}}}}},
+		},
+	}
+	if err := s.githubHandleHook(context.Background(), hook, "Backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if issue.Title != "boom" || !strings.Contains(issue.Body, "```\npanic(err)\n```") || !strings.Contains(issue.Body, hook.URL) {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+
+	tickets := s.tickets(hook)
+	if len(tickets) != 1 || tickets[0].Key != "acme/backend#12" || tickets[0].Destination != "github:Backend" {
+		t.Errorf("unexpected tickets %+v", tickets)
+	}
+}
@@ -0,0 +1,169 @@
+package slaxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// HTTPDestination posts alerts to any http endpoint, selected by "http:<name>" destinations
+// or the webhook path /webhook/sentry/http/<name>
+type HTTPDestination struct {
+	URL string `mapstructure:"url"`
+	// Method defaults to POST
+	Method  string            `mapstructure:"method"`
+	Headers map[string]string `mapstructure:"headers"`
+	// Template renders the JSON body from the webhook, eg: {"text": {{ json .Title }}},
+	// the webhook with its issue or metric alert data is sent if empty
+	Template string `mapstructure:"template"`
+
+	// Username and Password are sent as basic auth
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// BearerToken is sent as bearer auth
+	BearerToken string `mapstructure:"bearer-token"`
+
+	// SuccessCodes are the status codes of a successful request, defaults to all 2xx codes
+	SuccessCodes []int `mapstructure:"success-codes"`
+}
+
+// httpPayload is the body sent without a template, the webhook with its integration platform data
+type httpPayload struct {
+	*Webhook
+	Resource    string           `json:"resource,omitempty"`
+	Action      string           `json:"action,omitempty"`
+	Actor       *SentryActor     `json:"actor,omitempty"`
+	Issue       *SentryIssue     `json:"issue,omitempty"`
+	MetricAlert *MetricAlertData `json:"metric_alert,omitempty"`
+}
+
+// newHTTPPayload returns the body of the hook sent without a template
+func newHTTPPayload(hook *Webhook) httpPayload {
+	payload := httpPayload{
+		Webhook:     hook,
+		Resource:    hook.Resource,
+		Action:      hook.Action,
+		Issue:       hook.Issue,
+		MetricAlert: hook.MetricAlert,
+	}
+	if hook.Actor != (SentryActor{}) {
+		payload.Actor = &hook.Actor
+	}
+
+	return payload
+}
+
+// httpDestination is a compiled HTTPDestination
+type httpDestination struct {
+	HTTPDestination
+	body *template.Template
+}
+
+// compileHTTPDestinations compiles the templates of the http destinations
+func compileHTTPDestinations(cfg map[string]HTTPDestination) (map[string]*httpDestination, error) {
+	destinations := make(map[string]*httpDestination, len(cfg))
+	for name, d := range cfg {
+		if d.URL == "" {
+			return nil, fmt.Errorf("http destination %s has no url", name)
+		}
+
+		compiled := &httpDestination{HTTPDestination: d}
+		if d.Template != "" {
+			tpl, err := parseTemplate(name, d.Template)
+			if err != nil {
+				return nil, fmt.Errorf("invalid template of http destination %s, err: %w", name, err)
+			}
+			compiled.body = tpl
+		}
+
+		// viper lowercases map keys
+		destinations[strings.ToLower(name)] = compiled
+	}
+
+	return destinations, nil
+}
+
+func (s *server) httpHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	d, err := s.httpDestination(name)
+	if err != nil {
+		return err
+	}
+
+	body, err := d.render(hook)
+	if err != nil {
+		return err
+	}
+
+	req := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeaders(d.Headers).
+		SetBody(body)
+	if d.Username != "" || d.Password != "" {
+		req.SetBasicAuth(d.Username, d.Password)
+	}
+	if d.BearerToken != "" {
+		req.SetAuthToken(d.BearerToken)
+	}
+
+	method := strings.ToUpper(d.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	res, err := req.Execute(method, d.URL)
+	if err != nil {
+		return fmt.Errorf("failed to send http request to %s, err=%w", name, err)
+	}
+	if !d.isSuccess(res.StatusCode()) {
+		return fmt.Errorf("failed to send http request to %s, status=%d, response_body=%s", name, res.StatusCode(), res.Body())
+	}
+
+	return nil
+}
+
+// httpDestination returns the named http destination
+func (s *server) httpDestination(name string) (*httpDestination, error) {
+	d, ok := s.httpDestinations[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown http destination %q", name)
+	}
+
+	return d, nil
+}
+
+// render renders the JSON body of the hook
+func (d *httpDestination) render(hook *Webhook) ([]byte, error) {
+	if d.body == nil {
+		return json.Marshal(newHTTPPayload(hook))
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := d.body.Execute(buf, hook); err != nil {
+		return nil, fmt.Errorf("failed to render template of http destination %s, err: %w", d.body.Name(), err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("template of http destination %s rendered invalid json: %s", d.body.Name(), buf.String())
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isSuccess checks whether the status code means success
+func (d *httpDestination) isSuccess(code int) bool {
+	if len(d.SuccessCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+
+	for _, c := range d.SuccessCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
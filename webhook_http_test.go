@@ -0,0 +1,137 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPHandleHook(t *testing.T) {
+	var received *http.Request
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	s := New(Config{}, NewNullLogger()).(*server)
+	var err error
+	s.httpDestinations, err = compileHTTPDestinations(map[string]HTTPDestination{
+		"Tickets": {
+			URL:          srv.URL + "/tickets",
+			Method:       "put",
+			Headers:      map[string]string{"X-Source": "slaxy"},
+			BearerToken:  "secret",
+			SuccessCodes: []int{http.StatusCreated},
+			Template: `{
+				"summary": {{ json .Title }},
+				"project": {{ json (upper .ProjectName) }},
+				"server": {{ json (tag . "server_name") }},
+				"env": {{ json (default "none" .Event.Environment) }}
+			}`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Event: SentryEvent{
+			Title: `index "out" of range`,
+			Tags:  []SentryTag{{"server_name", "web-1"}},
+		},
+	}
+	if err := s.httpHandleHook(context.Background(), hook, "tickets"); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Method != http.MethodPut || received.URL.Path != "/tickets" {
+		t.Errorf("unexpected request %s %s", received.Method, received.URL)
+	}
+	if received.Header.Get("Authorization") != "Bearer secret" || received.Header.Get("X-Source") != "slaxy" {
+		t.Errorf("unexpected headers %v", received.Header)
+	}
+	expected := map[string]interface{}{"summary": `index "out" of range`, "project": "BACKEND", "server": "web-1", "env": "none"}
+	for key, value := range expected {
+		if body[key] != value {
+			t.Errorf("unexpected %s %v, expected %v", key, body[key], value)
+		}
+	}
+}
+
+func TestHTTPHandleHookFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := New(Config{}, NewNullLogger()).(*server)
+	var err error
+	s.httpDestinations, err = compileHTTPDestinations(map[string]HTTPDestination{
+		"strict":  {URL: srv.URL, SuccessCodes: []int{http.StatusAccepted}},
+		"broken":  {URL: srv.URL, Template: `{"title": {{ .Title }}}`},
+		"default": {URL: srv.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := &Webhook{Event: SentryEvent{Title: "boom"}}
+	if err := s.httpHandleHook(context.Background(), hook, "strict"); err == nil || !strings.Contains(err.Error(), "status=200") {
+		t.Errorf("expected an error for an unexpected status code, got %v", err)
+	}
+	if err := s.httpHandleHook(context.Background(), hook, "broken"); err == nil || !strings.Contains(err.Error(), "invalid json") {
+		t.Errorf("expected an error for invalid json, got %v", err)
+	}
+	if err := s.httpHandleHook(context.Background(), hook, "default"); err != nil {
+		t.Errorf("the webhook should be sent by default, got %v", err)
+	}
+}
+
+func TestHTTPDefaultBody(t *testing.T) {
+	hook, err := parseWebhook(resourceIssue, []byte(`{"action": "resolved", "actor": {"type": "user", "name": "Jane"},
+		"data": {"issue": {"id": "1", "shortId": "BACKEND-1", "title": "boom", "status": "resolved"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := (&httpDestination{}).render(hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		ID     string      `json:"id"`
+		Action string      `json:"action"`
+		Actor  SentryActor `json:"actor"`
+		Issue  SentryIssue `json:"issue"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != "1" || body.Action != issueResolved || body.Actor.Name != "Jane" || body.Issue.ShortID != "BACKEND-1" {
+		t.Errorf("the integration platform data should be sent, got %s", raw)
+	}
+}
+
+func TestCompileHTTPDestinationsErrors(t *testing.T) {
+	tests := map[string]HTTPDestination{
+		"missing url":      {Template: `{}`},
+		"invalid template": {URL: "http://localhost", Template: `{{ .Title `},
+		"unknown function": {URL: "http://localhost", Template: `{{ nope .Title }}`},
+	}
+
+	for name, d := range tests {
+		if _, err := compileHTTPDestinations(map[string]HTTPDestination{"test": d}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package slaxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// resourceHeader is set by the sentry integration platform, legacy plugin webhooks don't send it
+const resourceHeader = "Sentry-Hook-Resource"
+
+// sentry integration platform resources
+const (
+	resourceEventAlert   = "event_alert"
+	resourceIssue        = "issue"
+	resourceInstallation = "installation"
+)
+
+// errUnsupportedResource is returned for resources like "error" or "comment" which aren't posted
+var errUnsupportedResource = errors.New("unsupported resource")
+
+// integrationPayload is the envelope of all sentry integration platform webhooks
+type integrationPayload struct {
+	Action       string             `json:"action"` // "triggered", "created", "resolved"
+	Installation SentryInstallation `json:"installation"`
+	Data         json.RawMessage    `json:"data"`
+	Actor        SentryActor        `json:"actor"`
+}
+
+// SentryInstallation is the installation of the integration in a sentry organization
+type SentryInstallation struct {
+	UUID         string `json:"uuid"`
+	Status       string `json:"status"` // "installed", "pending"
+	Organization struct {
+		Slug string `json:"slug"`
+	} `json:"organization"`
+	App struct {
+		UUID string `json:"uuid"`
+		Slug string `json:"slug"`
+	} `json:"app"`
+}
+
+// SentryActor is the user or application that caused the webhook
+type SentryActor struct {
+	Type string `json:"type"` // "user", "application", "sentry"
+	Name string `json:"name"`
+}
+
+// eventAlertData is the data of an "event_alert" resource
+type eventAlertData struct {
+	Event         integrationEvent `json:"event"`
+	TriggeredRule string           `json:"triggered_rule"`
+}
+
+// integrationEvent is an event as sent by the integration platform
+type integrationEvent struct {
+	SentryEvent
+	URL      string `json:"url"` // "https://sentry.io/api/0/projects/{org}/{project}/events/{event_id}/"
+	WebURL   string `json:"web_url"`
+	IssueURL string `json:"issue_url"`
+	IssueID  string `json:"issue_id"`
+}
+
+// issueData is the data of an "issue" resource
+type issueData struct {
+	Issue SentryIssue `json:"issue"`
+}
+
+// SentryIssue is the issue of an "issue" resource
+type SentryIssue struct {
+	ID        string            `json:"id"`
+	ShortID   string            `json:"shortId"` // "SLAXY-1A"
+	Title     string            `json:"title"`
+	Culprit   string            `json:"culprit"`
+	Level     string            `json:"level"`
+	Status    string            `json:"status"` // "unresolved", "resolved", "ignored"
+	Platform  string            `json:"platform"`
+	Permalink string            `json:"permalink"`
+	WebURL    string            `json:"web_url"`
+	Metadata  SentryEvtMetadata `json:"metadata"`
+
+	StatusDetails SentryIssueStatusDetails `json:"statusDetails"`
+	AssignedTo    *SentryAssignee          `json:"assignedTo"`
+
+	Project struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	} `json:"project"`
+}
+
+// installationData is the data of an "installation" resource
+type installationData struct {
+	Installation SentryInstallation `json:"installation"`
+}
+
+// parseWebhook decodes a legacy plugin webhook or an integration platform resource into a webhook
+func parseWebhook(resource string, buf []byte) (*Webhook, error) {
+	hook := &Webhook{}
+
+	// legacy plugin webhooks don't send a resource header
+	if resource == "" {
+		if err := json.Unmarshal(buf, hook); err != nil {
+			return nil, err
+		}
+
+		return hook, nil
+	}
+
+	var payload integrationPayload
+	if err := json.Unmarshal(buf, &payload); err != nil {
+		return nil, err
+	}
+
+	hook.Resource = resource
+	hook.Action = payload.Action
+	hook.Actor = payload.Actor
+
+	switch resource {
+	case resourceEventAlert:
+		var data eventAlertData
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return nil, fmt.Errorf("invalid %s data: %w", resource, err)
+		}
+
+		hook.ID = data.Event.IssueID
+		hook.Culprit = data.Event.Culprit
+		hook.URL = data.Event.WebURL
+		hook.Level = data.Event.Level
+		hook.ProjectSlug = projectFromEventURL(data.Event.URL)
+		hook.ProjectName = hook.ProjectSlug
+		if data.TriggeredRule != "" {
+			hook.TriggeringRules = []string{data.TriggeredRule}
+		}
+		hook.Event = data.Event.SentryEvent
+		hook.organization = organizationFromEventURL(data.Event.URL)
+	case resourceIssue:
+		var data issueData
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return nil, fmt.Errorf("invalid %s data: %w", resource, err)
+		}
+
+		issue := data.Issue
+		hook.ID = issue.ID
+		hook.Culprit = issue.Culprit
+		hook.URL = issue.WebURL
+		if hook.URL == "" {
+			hook.URL = issue.Permalink
+		}
+		hook.Level = issue.Level
+		hook.ProjectName = issue.Project.Name
+		hook.ProjectSlug = issue.Project.Slug
+		hook.Event = SentryEvent{
+			Culprit:  issue.Culprit,
+			Title:    issue.Title,
+			Platform: issue.Platform,
+			Level:    issue.Level,
+			Metadata: issue.Metadata,
+		}
+		hook.Issue = &issue
+	case resourceInstallation:
+		var data installationData
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return nil, fmt.Errorf("invalid %s data: %w", resource, err)
+		}
+
+		installation := data.Installation
+		if installation.UUID == "" {
+			installation = payload.Installation
+		}
+		hook.Installation = &installation
+	case resourceMetricAlert:
+		if err := parseMetricAlert(hook, payload.Data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", errUnsupportedResource, resource)
+	}
+
+	return hook, nil
+}
+
+// projectFromEventURL extracts the project slug of an event api url
+// eg: https://sentry.io/api/0/projects/my-org/my-project/events/fec9f96296cb47d89e652d183e2752cf/
+func projectFromEventURL(url string) string {
+	parts := strings.Split(url, "/")
+	for i, part := range parts {
+		if part == "projects" && i+2 < len(parts) {
+			return parts[i+2]
+		}
+	}
+
+	return ""
+}
+
+// organizationFromEventURL returns the organization slug of an event api url
+func organizationFromEventURL(url string) string {
+	parts := strings.Split(url, "/")
+	for i, part := range parts {
+		if part == "projects" && i+2 < len(parts) {
+			return parts[i+1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,126 @@
+package slaxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseWebhookEventAlert(t *testing.T) {
+	payload := `{
+		"action": "triggered",
+		"installation": {"uuid": "a8e5d37a-696c-4c54-adb5-b3f28d64c7de"},
+		"data": {
+			"event": {
+				"event_id": "fec9f96296cb47d89e652d183e2752cf",
+				"issue_id": "1170820242",
+				"url": "https://sentry.io/api/0/projects/acme/backend/events/fec9f96296cb47d89e652d183e2752cf/",
+				"web_url": "https://sentry.io/organizations/acme/issues/1170820242/events/fec9f96296cb47d89e652d183e2752cf/",
+				"title": "this is an test error",
+				"culprit": "main.run",
+				"level": "error",
+				"environment": "develop",
+				"tags": [["environment", "develop"], ["server_name", "web-1"]]
+			},
+			"triggered_rule": "Send a notification for new issues"
+		},
+		"actor": {"type": "application", "name": "sentry"}
+	}`
+
+	hook, err := parseWebhook(resourceEventAlert, []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hook.ID != "1170820242" {
+		t.Errorf("unexpected issue id %q", hook.ID)
+	}
+	if hook.ProjectSlug != "backend" || hook.ProjectName != "backend" {
+		t.Errorf("unexpected project %q / %q", hook.ProjectSlug, hook.ProjectName)
+	}
+	if hook.URL == "" || hook.Culprit != "main.run" || hook.Level != "error" {
+		t.Errorf("unexpected hook %+v", hook)
+	}
+	if len(hook.TriggeringRules) != 1 || hook.TriggeringRules[0] != "Send a notification for new issues" {
+		t.Errorf("unexpected triggering rules %v", hook.TriggeringRules)
+	}
+	if hook.Event.Environment != "develop" || len(hook.Event.Tags) != 2 {
+		t.Errorf("unexpected event %+v", hook.Event)
+	}
+	if hook.Title() != "this is an test error" {
+		t.Errorf("unexpected title %q", hook.Title())
+	}
+}
+
+func TestParseWebhookIssue(t *testing.T) {
+	payload := `{
+		"action": "created",
+		"data": {
+			"issue": {
+				"id": "1170820242",
+				"shortId": "BACKEND-1A",
+				"title": "this is an test error",
+				"culprit": "main.run",
+				"level": "error",
+				"status": "unresolved",
+				"web_url": "https://sentry.io/organizations/acme/issues/1170820242/",
+				"project": {"id": "1", "name": "Backend", "slug": "backend"}
+			}
+		},
+		"actor": {"type": "user", "name": "Jane"}
+	}`
+
+	hook, err := parseWebhook(resourceIssue, []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hook.Issue == nil || hook.Issue.ShortID != "BACKEND-1A" {
+		t.Fatalf("issue not decoded: %+v", hook.Issue)
+	}
+	if hook.ID != "1170820242" || hook.ProjectName != "Backend" || hook.ProjectSlug != "backend" {
+		t.Errorf("unexpected hook %+v", hook)
+	}
+	if hook.Action != "created" || hook.Actor.Name != "Jane" {
+		t.Errorf("unexpected action %q by %q", hook.Action, hook.Actor.Name)
+	}
+}
+
+func TestParseWebhookInstallation(t *testing.T) {
+	payload := `{
+		"action": "created",
+		"data": {"installation": {"uuid": "a8e5d37a", "status": "installed", "app": {"slug": "slaxy"}}},
+		"actor": {"type": "user", "name": "Jane"}
+	}`
+
+	hook, err := parseWebhook(resourceInstallation, []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hook.Installation == nil || hook.Installation.App.Slug != "slaxy" {
+		t.Fatalf("installation not decoded: %+v", hook.Installation)
+	}
+}
+
+func TestParseWebhookUnsupportedResource(t *testing.T) {
+	if _, err := parseWebhook("comment", []byte(`{"action": "created", "data": {}}`)); !errors.Is(err, errUnsupportedResource) {
+		t.Errorf("expected an unsupported resource error, got %v", err)
+	}
+
+	s := New(Config{}, NewNullLogger()).(*server)
+	if err := setupTestServer(t, s); err != nil {
+		t.Fatal(err)
+	}
+
+	// sentry must not see a failed delivery
+	req := httptest.NewRequest(http.MethodPost, "/webhook/sentry/C0123", strings.NewReader(`{"action": "created", "data": {}}`))
+	req.Header.Set(resourceHeader, "comment")
+	rec := httptest.NewRecorder()
+	s.handleWebhook(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected unknown resources to be acknowledged, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package slaxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// issue resource actions
+const (
+	issueCreated    = "created"
+	issueResolved   = "resolved"
+	issueAssigned   = "assigned"
+	issueIgnored    = "ignored"
+	issueArchived   = "archived"
+	issueUnresolved = "unresolved"
+)
+
+// colors of the issue status changes
+const (
+	colorAssigned = "#6c5fc7"
+	colorIgnored  = "#9585a3"
+)
+
+// SentryIssueStatusDetails tell in which release or commit an issue was resolved and how long it is ignored
+type SentryIssueStatusDetails struct {
+	InRelease     string `json:"inRelease"`
+	InNextRelease bool   `json:"inNextRelease"`
+	InCommit      *struct {
+		Commit     string `json:"commit"`
+		Repository string `json:"repository"`
+	} `json:"inCommit"`
+	IgnoreUntil           string `json:"ignoreUntil"`
+	IgnoreCount           int    `json:"ignoreCount"`
+	IgnoreUserCount       int    `json:"ignoreUserCount"`
+	IgnoreUntilEscalating bool   `json:"ignoreUntilEscalating"`
+}
+
+// SentryAssignee is the user or team an issue got assigned to
+type SentryAssignee struct {
+	Type  string `json:"type"` // "user", "team"
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// isStatusChange reports whether the hook is an issue lifecycle change rather than a new alert
+func (w *Webhook) isStatusChange() bool {
+	return w.Issue != nil && w.Action != issueCreated
+}
+
+// isNewAlert reports whether the hook is an alert of an event rather than a lifecycle change,
+// a metric alert or a summary
+func (w *Webhook) isNewAlert() bool {
+	return w.Resource != resourceSummary && w.MetricAlert == nil && !w.isStatusChange()
+}
+
+// statusChange describes the issue or metric alert lifecycle change, eg: "Resolved in release v1.2.0 by Jane"
+func (w *Webhook) statusChange() string {
+	if w.Issue == nil && w.MetricAlert == nil {
+		return ""
+	}
+
+	var details SentryIssueStatusDetails
+	if w.Issue != nil {
+		details = w.Issue.StatusDetails
+	}
+	var msg string
+	switch w.Action {
+	case issueResolved:
+		msg = "Resolved"
+		switch {
+		case details.InRelease != "":
+			msg += " in release " + details.InRelease
+		case details.InNextRelease:
+			msg += " in the next release"
+		case details.InCommit != nil && details.InCommit.Commit != "":
+			msg += " in commit " + shortCommit(details.InCommit.Commit)
+		}
+	case issueAssigned:
+		msg = "Assigned"
+		if w.Issue != nil && w.Issue.AssignedTo != nil {
+			msg += " to " + w.Issue.AssignedTo.String()
+		}
+	case issueIgnored, issueArchived:
+		msg = "Ignored"
+		if w.Action == issueArchived {
+			msg = "Archived"
+		}
+		switch {
+		case details.IgnoreUntil != "":
+			msg += " until " + details.IgnoreUntil
+		case details.IgnoreCount > 0:
+			msg += fmt.Sprintf(" until it occurs %d more times", details.IgnoreCount)
+		case details.IgnoreUserCount > 0:
+			msg += fmt.Sprintf(" until it affects %d more users", details.IgnoreUserCount)
+		case details.IgnoreUntilEscalating:
+			msg += " until escalating"
+		}
+	case issueUnresolved:
+		msg = "Unresolved"
+		// sentry itself reopens issues which regress
+		if w.Actor.Type == "" || w.Actor.Type == "sentry" {
+			msg = "Regressed"
+		}
+	default:
+		if w.Action == "" {
+			return ""
+		}
+		msg = strings.ToUpper(w.Action[:1]) + w.Action[1:]
+	}
+
+	if w.Actor.Name != "" {
+		msg += " by " + w.Actor.Name
+	}
+
+	return msg
+}
+
+// isResolution reports whether the hook resolves an issue or metric alert
+func (w *Webhook) isResolution() bool {
+	return (w.Issue != nil && w.Action == issueResolved) || (w.MetricAlert != nil && w.Action == metricAlertResolved)
+}
+
+// isRegression reports whether the hook reopens a resolved issue
+func (w *Webhook) isRegression() bool {
+	return w.Issue != nil && w.Action == issueUnresolved
+}
+
+// statusColor returns the color of the issue lifecycle change
+func (w *Webhook) statusColor() string {
+	switch w.Action {
+	case issueResolved:
+		return colorResolved
+	case issueAssigned:
+		return colorAssigned
+	case issueIgnored, issueArchived:
+		return colorIgnored
+	default:
+		return colorCritical
+	}
+}
+
+// String returns the human readable assignee, eg: "team backend" or "Jane"
+func (a *SentryAssignee) String() string {
+	name := a.Name
+	if name == "" {
+		name = a.Email
+	}
+
+	if a.Type == "team" {
+		return "team " + name
+	}
+
+	return name
+}
+
+// shortCommit shortens a commit sha the way git does
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
@@ -0,0 +1,69 @@
+package slaxy
+
+import (
+	"testing"
+)
+
+func TestIssueStatusChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{
+			name: "resolved in release",
+			payload: `{"action": "resolved", "actor": {"type": "user", "name": "Jane"},
+				"data": {"issue": {"id": "1", "title": "boom", "status": "resolved", "statusDetails": {"inRelease": "v1.2.0"}}}}`,
+			want: "Resolved in release v1.2.0 by Jane",
+		},
+		{
+			name: "resolved in commit",
+			payload: `{"action": "resolved", "actor": {"type": "user", "name": "Jane"},
+				"data": {"issue": {"id": "1", "title": "boom", "statusDetails": {"inCommit": {"commit": "0123456789abcdef", "repository": "acme/backend"}}}}}`,
+			want: "Resolved in commit 0123456 by Jane",
+		},
+		{
+			name: "assigned to team",
+			payload: `{"action": "assigned", "actor": {"type": "user", "name": "Jane"},
+				"data": {"issue": {"id": "1", "title": "boom", "assignedTo": {"type": "team", "id": "3", "name": "backend"}}}}`,
+			want: "Assigned to team backend by Jane",
+		},
+		{
+			name: "ignored",
+			payload: `{"action": "ignored", "actor": {"type": "user", "name": "Jane"},
+				"data": {"issue": {"id": "1", "title": "boom", "statusDetails": {"ignoreCount": 100}}}}`,
+			want: "Ignored until it occurs 100 more times by Jane",
+		},
+		{
+			name:    "archived",
+			payload: `{"action": "archived", "data": {"issue": {"id": "1", "title": "boom", "statusDetails": {"ignoreUntilEscalating": true}}}}`,
+			want:    "Archived until escalating",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook, err := parseWebhook(resourceIssue, []byte(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hook.isStatusChange() {
+				t.Fatal("expected a status change")
+			}
+			if got := hook.statusChange(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIssueCreatedIsNoStatusChange(t *testing.T) {
+	hook, err := parseWebhook(resourceIssue, []byte(`{"action": "created", "data": {"issue": {"id": "1", "title": "boom"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hook.isStatusChange() {
+		t.Error("created issues should be rendered as full alerts")
+	}
+}
@@ -0,0 +1,116 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// JiraProject opens jira issues for alerts, selected by "jira:<name>" destinations
+type JiraProject struct {
+	// URL is the base url of jira, eg: https://acme.atlassian.net
+	URL string `mapstructure:"url"`
+	// Username and Token are used for basic auth, the token is an api token for jira cloud
+	Username string `mapstructure:"username"`
+	Token    string `mapstructure:"token"`
+	// Project is the key of the jira project, eg: "BACK"
+	Project string `mapstructure:"project"`
+	// IssueType defaults to "Bug"
+	IssueType string   `mapstructure:"issue-type"`
+	Labels    []string `mapstructure:"labels"`
+}
+
+// jiraIssue is the body of the create issue request of the jira rest api v2
+type jiraIssue struct {
+	Fields jiraIssueFields `json:"fields"`
+}
+
+type jiraIssueFields struct {
+	Project     jiraKey  `json:"project"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	IssueType   jiraName `json:"issuetype"`
+	Labels      []string `json:"labels,omitempty"`
+}
+
+type jiraKey struct {
+	Key string `json:"key"`
+}
+
+type jiraName struct {
+	Name string `json:"name"`
+}
+
+// jiraCreatedIssue is the response of the create issue request
+type jiraCreatedIssue struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// jiraError is the error response of the jira rest api
+type jiraError struct {
+	ErrorMessages []string          `json:"errorMessages"`
+	Errors        map[string]string `json:"errors"`
+}
+
+func (s *server) jiraHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	project, err := s.jiraProject(name)
+	if err != nil {
+		return err
+	}
+
+	return s.openTicket(hook, destination{kind: destinationJira, target: name}, func() (ticket, error) {
+		var created jiraCreatedIssue
+		var jiraErr jiraError
+		res, err := s.client.R().
+			SetContext(ctx).
+			SetBasicAuth(project.Username, project.Token).
+			SetBody(s.createJiraIssue(hook, project)).
+			SetResult(&created).
+			SetError(&jiraErr).
+			Post(strings.TrimSuffix(project.URL, "/") + "/rest/api/2/issue")
+		if err != nil {
+			return ticket{}, fmt.Errorf("failed to create jira issue, err=%w", err)
+		}
+		if res.StatusCode() >= 300 {
+			return ticket{}, fmt.Errorf("failed to create jira issue, status=%d, errors=%v %v", res.StatusCode(), jiraErr.ErrorMessages, jiraErr.Errors)
+		}
+
+		return ticket{
+			Key: created.Key,
+			URL: strings.TrimSuffix(project.URL, "/") + "/browse/" + created.Key,
+		}, nil
+	})
+}
+
+// jiraProject returns the named jira project
+func (s *server) jiraProject(name string) (JiraProject, error) {
+	if project, ok := lookupFold(s.cfg.JiraProjects, name); ok {
+		return project, nil
+	}
+
+	return JiraProject{}, fmt.Errorf("unknown jira project %q", name)
+}
+
+// createJiraIssue will create the jira issue of the hook, the description is jira wiki markup
+func (s *server) createJiraIssue(hook *Webhook, project JiraProject) jiraIssue {
+	issueType := project.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+
+	description := ticketDescription(hook)
+	if stacktrace := s.renderStacktrace(hook, jiraStacktrace, ticketStacktraceMax); stacktrace != "" {
+		description += "\n\nStacktrace:\n" + stacktrace
+	}
+
+	return jiraIssue{
+		Fields: jiraIssueFields{
+			Project:     jiraKey{Key: project.Project},
+			Summary:     truncate(strings.ReplaceAll(hook.Title(), "\n", " "), 255),
+			Description: description,
+			IssueType:   jiraName{Name: issueType},
+			Labels:      project.Labels,
+		},
+	}
+}
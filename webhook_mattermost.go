@@ -0,0 +1,141 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// mattermostMessage is a message for mattermost incoming webhooks
+type mattermostMessage struct {
+	Text        string                 `json:"text,omitempty"`
+	Username    string                 `json:"username,omitempty"`
+	IconURL     string                 `json:"icon_url,omitempty"`
+	Attachments []mattermostAttachment `json:"attachments"`
+}
+
+// mattermostAttachment is the subset of slack attachment properties mattermost understands
+type mattermostAttachment struct {
+	Fallback   string            `json:"fallback"`
+	Color      string            `json:"color,omitempty"`
+	Text       string            `json:"text,omitempty"`
+	Title      string            `json:"title,omitempty"`
+	TitleLink  string            `json:"title_link,omitempty"`
+	Fields     []mattermostField `json:"fields,omitempty"`
+	Footer     string            `json:"footer,omitempty"`
+	FooterIcon string            `json:"footer_icon,omitempty"`
+}
+
+type mattermostField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (s *server) mattermostHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	url, err := s.mattermostWebhookURL(name)
+	if err != nil {
+		return err
+	}
+
+	message := s.createMattermostMessage(hook)
+	res, err := s.client.R().SetContext(ctx).SetBody(message).Post(url)
+	if err != nil {
+		return fmt.Errorf("failed to send mattermost message, err=%w", err)
+	}
+	if res.StatusCode() >= 300 {
+		return fmt.Errorf("failed to send mattermost message, response_body=%s", res.Body())
+	}
+
+	return nil
+}
+
+// mattermostWebhookURL returns the url of the named mattermost webhook
+func (s *server) mattermostWebhookURL(name string) (string, error) {
+	if url, ok := lookupFold(s.cfg.MattermostWebhooks, name); ok {
+		return url, nil
+	}
+
+	return "", fmt.Errorf("unknown mattermost webhook %q", name)
+}
+
+// createMattermostMessage will create the mattermost message of the slack attachment.
+// Mattermost speaks common markdown instead of slack mrkdwn, needs a fallback for notifications
+// and ignores the timestamp of attachments.
+func (s *server) createMattermostMessage(hook *Webhook) mattermostMessage {
+	attachment := s.createAttachment(hook)
+
+	fields := make([]mattermostField, 0, len(attachment.Fields))
+	for _, field := range attachment.Fields {
+		// mattermost renders empty fields as a lonely title
+		if field.Value == "" {
+			continue
+		}
+		fields = append(fields, mattermostField{
+			Title: field.Title,
+			Value: mrkdwnToMarkdown(field.Value),
+			Short: field.Short,
+		})
+	}
+
+	return mattermostMessage{
+		Username: "Slaxy",
+		IconURL:  attachment.FooterIcon,
+		Attachments: []mattermostAttachment{
+			{
+				Fallback:   attachmentFallback(attachment),
+				Color:      attachment.Color,
+				Text:       mrkdwnToMarkdown(attachment.Text),
+				Title:      attachment.Title,
+				TitleLink:  attachment.TitleLink,
+				Fields:     fields,
+				Footer:     attachment.Footer,
+				FooterIcon: attachment.FooterIcon,
+			},
+		},
+	}
+}
+
+// attachmentFallback returns the plain text summary of the attachment shown in notifications
+func attachmentFallback(attachment slack.Attachment) string {
+	if attachment.Fallback != "" {
+		return attachment.Fallback
+	}
+	if attachment.Title != "" {
+		return attachment.Title
+	}
+
+	return attachment.Text
+}
+
+var (
+	mrkdwnLink   = regexp.MustCompile(`<(https?://[^|>]+)\|([^>]+)>`)
+	mrkdwnURL    = regexp.MustCompile(`<(https?://[^|>]+)>`)
+	mrkdwnBold   = regexp.MustCompile(`(^|[\s(])\*([^*\s](?:[^*\n]*[^*\s])?)\*`)
+	mrkdwnStrike = regexp.MustCompile(`(^|[\s(])~([^~\s](?:[^~\n]*[^~\s])?)~`)
+)
+
+// mrkdwnToMarkdown converts slack mrkdwn into common markdown, eg: "<url|text>" into "[text](url)" and "*bold*" into "**bold**"
+func mrkdwnToMarkdown(text string) string {
+	text = mrkdwnLinksToMarkdown(text)
+
+	// leave code alone, every odd part is inside backticks
+	parts := strings.Split(text, "`")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = mrkdwnBold.ReplaceAllString(parts[i], "$1**$2**")
+		parts[i] = mrkdwnStrike.ReplaceAllString(parts[i], "$1~~$2~~")
+	}
+
+	return strings.Join(parts, "`")
+}
+
+// mrkdwnLinksToMarkdown converts slack links like "<url|text>" into markdown links like "[text](url)"
+func mrkdwnLinksToMarkdown(text string) string {
+	text = mrkdwnLink.ReplaceAllString(text, "[$2]($1)")
+	text = mrkdwnURL.ReplaceAllString(text, "$1")
+
+	return text
+}
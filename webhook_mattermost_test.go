@@ -0,0 +1,77 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMattermostHandleHook(t *testing.T) {
+	received := make(chan mattermostMessage, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message mattermostMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+		received <- message
+	}))
+	defer srv.Close()
+
+	s := New(Config{MattermostWebhooks: map[string]string{"platform": srv.URL}}, NewNullLogger()).(*server)
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Culprit:     "main.run",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/1/",
+		Event: SentryEvent{
+			Title: "boom",
+			Tags:  []SentryTag{{"server_name", "web-1"}},
+		},
+	}
+	if err := s.mattermostHandleHook(context.Background(), hook, "Platform"); err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-received
+	if len(message.Attachments) != 1 {
+		t.Fatalf("unexpected attachments %+v", message.Attachments)
+	}
+
+	attachment := message.Attachments[0]
+	if attachment.Title != "boom" || attachment.Fallback != "boom" || attachment.TitleLink != hook.URL {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+	fields := map[string]string{}
+	for _, field := range attachment.Fields {
+		fields[field.Title] = field.Value
+	}
+	if fields["Project"] != "backend" || fields["Server Name"] != "web-1" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if _, ok := fields["Stacktrace"]; ok {
+		t.Error("empty fields should be skipped")
+	}
+
+	if err := s.mattermostHandleHook(context.Background(), hook, "unknown"); err == nil {
+		t.Error("expected an error for an unknown webhook")
+	}
+}
+
+func TestMrkdwnToMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"<https://sentry.io/issues/1/|BACKEND-1A> was *resolved*": "[BACKEND-1A](https://sentry.io/issues/1/) was **resolved**",
+		"see <https://sentry.io>":                                 "see https://sentry.io",
+		"~ignored~ (*again*)":                                     "~~ignored~~ (**again**)",
+		"2 * 3 * 4":                                               "2 * 3 * 4",
+		"```\nfoo(*bar*)\n```":                                    "```\nfoo(*bar*)\n```",
+	}
+
+	for in, expected := range tests {
+		if got := mrkdwnToMarkdown(in); got != expected {
+			t.Errorf("mrkdwnToMarkdown(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
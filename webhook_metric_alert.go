@@ -0,0 +1,170 @@
+package slaxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const resourceMetricAlert = "metric_alert"
+
+// metric alert actions, they reflect the trigger state of the alert, "critical" being the default
+const (
+	metricAlertWarning  = "warning"
+	metricAlertResolved = "resolved"
+)
+
+// colors of the metric alert trigger states
+const (
+	colorCritical = "#f43f20"
+	colorWarning  = "#f2b036"
+	colorResolved = "#2eb67d"
+)
+
+// MetricAlertData is the data of a "metric_alert" resource
+type MetricAlertData struct {
+	MetricAlert      MetricAlert `json:"metric_alert"`
+	DescriptionText  string      `json:"description_text"`  // "1000 events in the last 10 minutes\nFilter: level:error"
+	DescriptionTitle string      `json:"description_title"` // "Critical: Too many errors"
+	WebURL           string      `json:"web_url"`
+}
+
+// MetricAlert is the incident which was opened, changed or closed by the alert rule
+type MetricAlert struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Projects     []string        `json:"projects"`
+	DateStarted  string          `json:"date_started"`
+	DateDetected string          `json:"date_detected"`
+	DateClosed   string          `json:"date_closed"`
+	AlertRule    MetricAlertRule `json:"alert_rule"`
+}
+
+// MetricAlertRule is the alert rule of a metric alert
+type MetricAlertRule struct {
+	ID               string               `json:"id"`
+	Name             string               `json:"name"`
+	Aggregate        string               `json:"aggregate"` // "count()", "p95(transaction.duration)"
+	Query            string               `json:"query"`
+	Environment      string               `json:"environment"`
+	ThresholdType    int                  `json:"threshold_type"` // 0 above, 1 below
+	ResolveThreshold *float64             `json:"resolve_threshold"`
+	TimeWindow       float64              `json:"time_window"` // minutes
+	Triggers         []MetricAlertTrigger `json:"triggers"`
+}
+
+// MetricAlertTrigger is a threshold of an alert rule
+type MetricAlertTrigger struct {
+	Label            string   `json:"label"` // "critical", "warning"
+	ThresholdType    int      `json:"threshold_type"`
+	AlertThreshold   *float64 `json:"alert_threshold"`
+	ResolveThreshold *float64 `json:"resolve_threshold"`
+}
+
+// parseMetricAlert decodes the data of a metric alert into the hook
+func parseMetricAlert(hook *Webhook, raw json.RawMessage) error {
+	var data MetricAlertData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("invalid %s data: %w", resourceMetricAlert, err)
+	}
+
+	alert := data.MetricAlert
+	hook.ID = alert.ID
+	hook.URL = data.WebURL
+	hook.Level = hook.Action
+	hook.Message = data.DescriptionTitle
+	hook.ProjectName = strings.Join(alert.Projects, ", ")
+	if len(alert.Projects) == 1 {
+		hook.ProjectSlug = alert.Projects[0]
+	}
+	hook.TriggeringRules = []string{alert.AlertRule.Name}
+	hook.Event = SentryEvent{
+		Title:       data.DescriptionTitle,
+		Environment: alert.AlertRule.Environment,
+	}
+	hook.MetricAlert = &data
+
+	return nil
+}
+
+// color returns the color of the trigger state
+func (d *MetricAlertData) color(action string) string {
+	switch action {
+	case metricAlertResolved:
+		return colorResolved
+	case metricAlertWarning:
+		return colorWarning
+	default:
+		return colorCritical
+	}
+}
+
+// state returns the human readable trigger state
+func (d *MetricAlertData) state(action string) string {
+	if action == "" {
+		return "Unknown"
+	}
+
+	return strings.ToUpper(action[:1]) + action[1:]
+}
+
+// threshold returns the threshold of the trigger matching the action, eg: "above 100"
+func (d *MetricAlertData) threshold(action string) string {
+	rule := d.MetricAlert.AlertRule
+	direction := "above"
+	if rule.ThresholdType == 1 {
+		direction = "below"
+	}
+
+	if action == metricAlertResolved {
+		if rule.ResolveThreshold == nil {
+			return ""
+		}
+		// resolving happens in the opposite direction
+		if rule.ThresholdType == 1 {
+			return "above " + formatThreshold(*rule.ResolveThreshold)
+		}
+		return "below " + formatThreshold(*rule.ResolveThreshold)
+	}
+
+	for _, trigger := range rule.Triggers {
+		if trigger.Label == action && trigger.AlertThreshold != nil {
+			return direction + " " + formatThreshold(*trigger.AlertThreshold)
+		}
+	}
+
+	return ""
+}
+
+// currentValue returns the first line of the description, which sentry fills with the metric value
+// eg: "1000 events in the last 10 minutes"
+func (d *MetricAlertData) currentValue() string {
+	lines := strings.SplitN(d.DescriptionText, "\n", 2)
+	return lines[0]
+}
+
+// details returns the remaining description lines, eg: "Filter: level:error"
+func (d *MetricAlertData) details() string {
+	lines := strings.SplitN(d.DescriptionText, "\n", 2)
+	if len(lines) < 2 {
+		return ""
+	}
+
+	return lines[1]
+}
+
+// formatThreshold formats a threshold without needless decimals
+func formatThreshold(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// colorToInt converts a "#rrggbb" color into its integer representation
+func colorToInt(color string) int {
+	i, err := strconv.ParseInt(strings.TrimPrefix(color, "#"), 16, 32)
+	if err != nil {
+		return 0
+	}
+
+	return int(i)
+}
@@ -0,0 +1,88 @@
+package slaxy
+
+import (
+	"testing"
+)
+
+const metricAlertPayload = `{
+	"action": "critical",
+	"data": {
+		"metric_alert": {
+			"id": "81",
+			"title": "Too many errors",
+			"projects": ["backend"],
+			"date_started": "2022-02-24T03:08:36.893372Z",
+			"alert_rule": {
+				"id": "12",
+				"name": "Error rate",
+				"aggregate": "count()",
+				"environment": "production",
+				"threshold_type": 0,
+				"resolve_threshold": 50,
+				"time_window": 10,
+				"triggers": [
+					{"label": "critical", "alert_threshold": 100},
+					{"label": "warning", "alert_threshold": 75.5}
+				]
+			}
+		},
+		"description_text": "1000 events in the last 10 minutes\nFilter: level:error",
+		"description_title": "Critical: Too many errors",
+		"web_url": "https://sentry.io/organizations/acme/alerts/rules/details/12/"
+	}
+}`
+
+func TestParseMetricAlert(t *testing.T) {
+	hook, err := parseWebhook(resourceMetricAlert, []byte(metricAlertPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alert := hook.MetricAlert
+	if alert == nil {
+		t.Fatal("metric alert not decoded")
+	}
+	if hook.Title() != "Critical: Too many errors" {
+		t.Errorf("unexpected title %q", hook.Title())
+	}
+	if got := alert.threshold("critical"); got != "above 100" {
+		t.Errorf("unexpected critical threshold %q", got)
+	}
+	if got := alert.threshold("warning"); got != "above 75.5" {
+		t.Errorf("unexpected warning threshold %q", got)
+	}
+	if got := alert.threshold("resolved"); got != "below 50" {
+		t.Errorf("unexpected resolve threshold %q", got)
+	}
+	if got := alert.currentValue(); got != "1000 events in the last 10 minutes" {
+		t.Errorf("unexpected current value %q", got)
+	}
+	if hook.Event.Environment != "production" || hook.ProjectSlug != "backend" {
+		t.Errorf("unexpected hook %+v", hook)
+	}
+}
+
+func TestMetricAlertRenderers(t *testing.T) {
+	s := New(Config{}, NewNullLogger()).(*server)
+
+	for action, color := range map[string]string{"critical": colorCritical, "warning": colorWarning, "resolved": colorResolved} {
+		hook, err := parseWebhook(resourceMetricAlert, []byte(metricAlertPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hook.Action = action
+
+		attachment := s.createAttachment(hook)
+		if attachment.Color != color {
+			t.Errorf("%s: unexpected slack color %q", action, attachment.Color)
+		}
+		if attachment.TitleLink != hook.URL {
+			t.Errorf("%s: unexpected slack title link %q", action, attachment.TitleLink)
+		}
+
+		message := s.createDiscordMessage(hook, discordFieldValueMax)
+		if len(message.Embeds) != 1 || message.Embeds[0].Color != colorToInt(color) {
+			t.Errorf("%s: unexpected discord embed %+v", action, message.Embeds)
+		}
+	}
+}
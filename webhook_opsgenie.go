@@ -0,0 +1,154 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/innogames/slaxy/version"
+)
+
+// opsgenieAPIURL is the default opsgenie api, use https://api.eu.opsgenie.com for the eu instance
+const opsgenieAPIURL = "https://api.opsgenie.com"
+
+// opsgenieAlert is the body of the create alert request of the opsgenie alert api
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+}
+
+// opsgenieClose is the body of the close alert request
+type opsgenieClose struct {
+	Source string `json:"source,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// opsgenieResponse is the response of the alert api
+type opsgenieResponse struct {
+	Result    string `json:"result"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId"`
+}
+
+func (s *server) opsgenieHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	apiKey, err := s.opsgenieAPIKey(name)
+	if err != nil {
+		return err
+	}
+
+	action := pageActionOf(hook)
+	if action == pageSkip {
+		return nil
+	}
+
+	apiURL := strings.TrimSuffix(s.cfg.OpsgenieAPIURL, "/")
+	if apiURL == "" {
+		apiURL = opsgenieAPIURL
+	}
+
+	var body interface{}
+	endpoint := apiURL + "/v2/alerts"
+	if action == pageResolve {
+		if hook.issueKey() == "" {
+			return nil
+		}
+		endpoint = fmt.Sprintf("%s/%s/close?identifierType=alias", endpoint, url.PathEscape(hook.issueKey()))
+		body = opsgenieClose{Source: "Slaxy v" + version.Version, Note: hook.statusChange()}
+	} else {
+		body = s.createOpsgenieAlert(hook)
+	}
+
+	var result opsgenieResponse
+	res, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Authorization", "GenieKey "+apiKey).
+		SetBody(body).
+		SetResult(&result).
+		SetError(&result).
+		Post(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to send opsgenie alert, err=%w", err)
+	}
+	if res.StatusCode() >= 300 {
+		return fmt.Errorf("failed to send opsgenie alert, status=%d, message=%s", res.StatusCode(), result.Message)
+	}
+
+	d := destination{kind: destinationOpsgenie, target: name}
+	if action == pageResolve {
+		return s.forgetPage(hook, d)
+	}
+
+	return s.rememberPage(hook, d)
+}
+
+// opsgenieAPIKey returns the api key of the named opsgenie integration
+func (s *server) opsgenieAPIKey(name string) (string, error) {
+	if key, ok := lookupFold(s.cfg.OpsgenieAPIKeys, name); ok {
+		return key, nil
+	}
+
+	return "", fmt.Errorf("unknown opsgenie integration %q", name)
+}
+
+// createOpsgenieAlert will create the opsgenie alert of the hook, the alias is the sentry issue
+func (s *server) createOpsgenieAlert(hook *Webhook) opsgenieAlert {
+	details := s.pageDetails(hook)
+	if hook.URL != "" {
+		details["Sentry"] = hook.URL
+	}
+
+	tags := []string{"sentry"}
+	if hook.ProjectSlug != "" {
+		tags = append(tags, hook.ProjectSlug)
+	}
+	if env := hook.environment(); env != "" {
+		tags = append(tags, env)
+	}
+
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var description strings.Builder
+	if hook.URL != "" {
+		fmt.Fprintf(&description, "%s\n\n", hook.URL)
+	}
+	for _, key := range keys {
+		fmt.Fprintf(&description, "%s: %s\n", key, details[key])
+	}
+
+	return opsgenieAlert{
+		Message:     truncate(hook.Title(), 130),
+		Alias:       hook.issueKey(),
+		Description: truncate(description.String(), 15000),
+		Tags:        tags,
+		Details:     details,
+		Entity:      hook.Culprit,
+		Source:      "Slaxy v" + version.Version,
+		Priority:    opsgeniePriority(pageSeverity(hook)),
+	}
+}
+
+// opsgeniePriority maps the severity to an opsgenie priority
+func opsgeniePriority(severity string) string {
+	switch severity {
+	case "critical":
+		return "P1"
+	case "error":
+		return "P2"
+	case "warning":
+		return "P3"
+	default:
+		return "P4"
+	}
+}
@@ -0,0 +1,66 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpsgenieCreateAndClose(t *testing.T) {
+	var requests []*http.Request
+	var alert opsgenieAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.URL.Path == "/v2/alerts" {
+			if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+				t.Error(err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"result": "Request will be processed", "requestId": "1"}`))
+	}))
+	defer srv.Close()
+
+	s := New(Config{
+		OpsgenieAPIURL:  srv.URL,
+		OpsgenieAPIKeys: map[string]string{"backend": "genie-key"},
+	}, NewNullLogger()).(*server)
+
+	trigger := &Webhook{
+		ID:          "42",
+		ProjectName: "Backend",
+		ProjectSlug: "backend",
+		Culprit:     "main.run",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/42/",
+		Event:       SentryEvent{Title: "boom", Environment: "production"},
+	}
+	if err := s.opsgenieHandleHook(context.Background(), trigger, "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := &Webhook{ID: "42", Resource: resourceIssue, Action: issueResolved, Issue: &SentryIssue{}}
+	if err := s.opsgenieHandleHook(context.Background(), resolved, "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected two requests, got %d", len(requests))
+	}
+	if got := requests[0].Header.Get("Authorization"); got != "GenieKey genie-key" {
+		t.Errorf("unexpected authorization %q", got)
+	}
+	if alert.Alias != "issue/42" || alert.Priority != "P2" || alert.Message != "boom" || alert.Details["Sentry"] != trigger.URL {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if requests[1].URL.EscapedPath() != "/v2/alerts/issue%2F42/close" || requests[1].URL.Query().Get("identifierType") != "alias" {
+		t.Errorf("unexpected close request %s", requests[1].URL)
+	}
+
+	if err := s.opsgenieHandleHook(context.Background(), trigger, "unknown"); err == nil {
+		t.Error("expected an error for an unknown integration")
+	}
+}
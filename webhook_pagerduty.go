@@ -0,0 +1,129 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/innogames/slaxy/version"
+)
+
+// pagerDutyEventsURL is the default pagerduty events api v2 endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutyEvent is an event of the pagerduty events api v2
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Client      string            `json:"client,omitempty"`
+	ClientURL   string            `json:"client_url,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// pagerDutyResponse is the response of the events api
+type pagerDutyResponse struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Errors  []string `json:"errors"`
+}
+
+func (s *server) pagerDutyHandleHook(ctx context.Context, hook *Webhook, service string) error {
+	routingKey, err := s.pagerDutyRoutingKey(service)
+	if err != nil {
+		return err
+	}
+
+	action := pageActionOf(hook)
+	if action == pageSkip {
+		return nil
+	}
+
+	event := s.createPagerDutyEvent(hook, routingKey, action)
+	if action == pageResolve && event.DedupKey == "" {
+		return nil
+	}
+
+	url := s.cfg.PagerDutyURL
+	if url == "" {
+		url = pagerDutyEventsURL
+	}
+
+	var result pagerDutyResponse
+	res, err := s.client.R().SetContext(ctx).SetBody(event).SetResult(&result).SetError(&result).Post(url)
+	if err != nil {
+		return fmt.Errorf("failed to send pagerduty event, err=%w", err)
+	}
+	if res.StatusCode() >= 300 {
+		return fmt.Errorf("failed to send pagerduty event, status=%d, message=%s, errors=%v", res.StatusCode(), result.Message, result.Errors)
+	}
+
+	d := destination{kind: destinationPagerDuty, target: service}
+	if action == pageResolve {
+		return s.forgetPage(hook, d)
+	}
+
+	return s.rememberPage(hook, d)
+}
+
+// pagerDutyRoutingKey returns the integration key of the named pagerduty service
+func (s *server) pagerDutyRoutingKey(service string) (string, error) {
+	if key, ok := lookupFold(s.cfg.PagerDutyRoutingKeys, service); ok {
+		return key, nil
+	}
+
+	return "", fmt.Errorf("unknown pagerduty service %q", service)
+}
+
+// createPagerDutyEvent will create the trigger or resolve event of the hook, the dedup key is the sentry issue
+func (s *server) createPagerDutyEvent(hook *Webhook, routingKey string, action pageAction) pagerDutyEvent {
+	event := pagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: "trigger",
+		DedupKey:    hook.issueKey(),
+	}
+	if action == pageResolve {
+		event.EventAction = "resolve"
+		return event
+	}
+
+	source := hook.tagValue("server_name")
+	if source == "" {
+		source = hook.ProjectName
+	}
+	if source == "" {
+		source = "sentry"
+	}
+
+	event.Payload = &pagerDutyPayload{
+		Summary:       truncate(hook.Title(), 1024),
+		Source:        source,
+		Severity:      pageSeverity(hook),
+		Component:     hook.Culprit,
+		Group:         hook.ProjectName,
+		Class:         hook.level(),
+		CustomDetails: s.pageDetails(hook),
+	}
+	event.Client = "Slaxy v" + version.Version
+	event.ClientURL = hook.URL
+	if hook.URL != "" {
+		event.Links = []pagerDutyLink{{Href: hook.URL, Text: "Open in Sentry"}}
+	}
+
+	return event
+}
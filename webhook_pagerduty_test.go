@@ -0,0 +1,100 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPagerDutyTriggerAndResolve(t *testing.T) {
+	var events []pagerDutyEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		events = append(events, event)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status": "success", "dedup_key": "issue/42"}`))
+	}))
+	defer srv.Close()
+
+	s := New(Config{
+		PagerDutyURL:         srv.URL,
+		PagerDutyRoutingKeys: map[string]string{"backend": "routing-key"},
+		Routes:               []Route{{Level: "fatal", Destinations: []string{"pagerduty:backend"}}},
+	}, NewNullLogger()).(*server)
+	routes, err := compileRoutes(s.cfg.Routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.routes = routes
+
+	trigger := &Webhook{
+		ID:          "42",
+		ProjectName: "backend",
+		Culprit:     "main.run",
+		Level:       "fatal",
+		URL:         "https://sentry.io/organizations/acme/issues/42/",
+		Event: SentryEvent{
+			Title: "boom",
+			Tags:  []SentryTag{{"server_name", "web-1"}},
+		},
+	}
+	if err := s.notify(context.Background(), trigger, destination{kind: destinationSlack}); err != nil {
+		t.Fatal(err)
+	}
+
+	// issue webhooks of the lifecycle carry the issue level, the resolution isn't routed to pagerduty
+	assigned := &Webhook{ID: "42", Resource: resourceIssue, Action: issueAssigned, Issue: &SentryIssue{}}
+	resolved := &Webhook{ID: "42", Resource: resourceIssue, Action: issueResolved, Issue: &SentryIssue{}}
+	for _, hook := range []*Webhook{assigned, resolved} {
+		if err := s.notify(context.Background(), hook, destination{kind: destinationSlack}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected a trigger and a resolve event, got %+v", events)
+	}
+	if events[0].EventAction != "trigger" || events[0].DedupKey != "issue/42" || events[0].RoutingKey != "routing-key" {
+		t.Errorf("unexpected trigger event %+v", events[0])
+	}
+	if p := events[0].Payload; p == nil || p.Severity != "critical" || p.Source != "web-1" || p.Summary != "boom" {
+		t.Errorf("unexpected trigger payload %+v", p)
+	}
+	if len(events[0].Links) != 1 || events[0].Links[0].Href != trigger.URL {
+		t.Errorf("unexpected links %+v", events[0].Links)
+	}
+	if events[1].EventAction != "resolve" || events[1].DedupKey != "issue/42" || events[1].Payload != nil {
+		t.Errorf("unexpected resolve event %+v", events[1])
+	}
+
+	if paged := s.pagedDestinations(resolved); len(paged) != 0 {
+		t.Errorf("resolved pages should be forgotten, got %v", paged)
+	}
+}
+
+func TestPageSeverity(t *testing.T) {
+	tests := map[string]string{
+		"fatal":   "critical",
+		"error":   "error",
+		"warning": "warning",
+		"info":    "info",
+		"":        "error",
+	}
+
+	for level, expected := range tests {
+		if got := pageSeverity(&Webhook{Level: level}); got != expected {
+			t.Errorf("pageSeverity(%q) = %q, expected %q", level, got, expected)
+		}
+	}
+
+	warning := &Webhook{Action: metricAlertWarning, MetricAlert: &MetricAlertData{}}
+	if got := pageSeverity(warning); got != "warning" {
+		t.Errorf("unexpected metric alert severity %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rocketChatMessage is a message for rocket.chat incoming webhooks
+type rocketChatMessage struct {
+	Text        string                 `json:"text,omitempty"`
+	Alias       string                 `json:"alias,omitempty"`
+	Avatar      string                 `json:"avatar,omitempty"`
+	Attachments []rocketChatAttachment `json:"attachments"`
+}
+
+// rocketChatAttachment is the subset of slack attachment properties rocket.chat understands
+type rocketChatAttachment struct {
+	Color             string            `json:"color,omitempty"`
+	Text              string            `json:"text,omitempty"`
+	Title             string            `json:"title,omitempty"`
+	TitleLink         string            `json:"title_link,omitempty"`
+	TitleLinkDownload bool              `json:"title_link_download"`
+	Ts                string            `json:"ts,omitempty"`
+	Fields            []rocketChatField `json:"fields,omitempty"`
+}
+
+type rocketChatField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (s *server) rocketChatHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	url, err := s.rocketChatWebhookURL(name)
+	if err != nil {
+		return err
+	}
+
+	message := s.createRocketChatMessage(hook)
+	res, err := s.client.R().SetContext(ctx).SetBody(message).Post(url)
+	if err != nil {
+		return fmt.Errorf("failed to send rocket.chat message, err=%w", err)
+	}
+	if res.StatusCode() >= 300 {
+		return fmt.Errorf("failed to send rocket.chat message, response_body=%s", res.Body())
+	}
+
+	return nil
+}
+
+// rocketChatWebhookURL returns the url of the named rocket.chat webhook
+func (s *server) rocketChatWebhookURL(name string) (string, error) {
+	if url, ok := lookupFold(s.cfg.RocketChatWebhooks, name); ok {
+		return url, nil
+	}
+
+	return "", fmt.Errorf("unknown rocket.chat webhook %q", name)
+}
+
+// createRocketChatMessage will create the rocket.chat message of the slack attachment.
+// Rocket.chat keeps slack's *bold* but wants markdown links, expects an ISO 8601 timestamp,
+// has no footer and doesn't render markdown in field values, so long fields like the stacktrace
+// become part of the attachment text.
+func (s *server) createRocketChatMessage(hook *Webhook) rocketChatMessage {
+	attachment := s.createAttachment(hook)
+
+	var text []string
+	if attachment.Text != "" {
+		text = append(text, mrkdwnLinksToMarkdown(attachment.Text))
+	}
+
+	fields := make([]rocketChatField, 0, len(attachment.Fields))
+	for _, field := range attachment.Fields {
+		if field.Value == "" {
+			continue
+		}
+		if !field.Short && strings.Contains(field.Value, "```") {
+			text = append(text, fmt.Sprintf("*%s*\n%s", field.Title, mrkdwnLinksToMarkdown(field.Value)))
+			continue
+		}
+		fields = append(fields, rocketChatField{
+			Title: field.Title,
+			Value: field.Value,
+			Short: field.Short,
+		})
+	}
+
+	return rocketChatMessage{
+		Alias:  attachment.Footer,
+		Avatar: attachment.FooterIcon,
+		Attachments: []rocketChatAttachment{
+			{
+				Color:     attachment.Color,
+				Text:      strings.Join(text, "\n"),
+				Title:     attachment.Title,
+				TitleLink: attachment.TitleLink,
+				Ts:        rocketChatTimestamp(attachment.Ts.String()),
+				Fields:    fields,
+			},
+		},
+	}
+}
+
+// rocketChatTimestamp converts the unix timestamp of a slack attachment into an ISO 8601 timestamp
+func rocketChatTimestamp(ts string) string {
+	if ts == "" {
+		return ""
+	}
+
+	seconds, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return ""
+	}
+
+	return time.Unix(int64(seconds), 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,70 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRocketChatHandleHook(t *testing.T) {
+	received := make(chan rocketChatMessage, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message rocketChatMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+		received <- message
+	}))
+	defer srv.Close()
+
+	s := New(Config{RocketChatWebhooks: map[string]string{"games": srv.URL}}, NewNullLogger()).(*server)
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Culprit:     "main.run",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/1/",
+		Event: SentryEvent{
+			Title: "boom",
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+			}}}}},
+		},
+	}
+	if err := s.rocketChatHandleHook(context.Background(), hook, "GAMES"); err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-received
+	if len(message.Attachments) != 1 || !strings.HasPrefix(message.Alias, "Slaxy v") {
+		t.Fatalf("unexpected message %+v", message)
+	}
+
+	attachment := message.Attachments[0]
+	if attachment.Title != "boom" || attachment.TitleLink != hook.URL {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+	if !strings.Contains(attachment.Text, "*Stacktrace*\n") || !strings.Contains(attachment.Text, "panic(err)") {
+		t.Errorf("stacktrace should be part of the text, got %q", attachment.Text)
+	}
+	for _, field := range attachment.Fields {
+		if field.Title == "Stacktrace" {
+			t.Error("stacktrace should not be a field")
+		}
+	}
+	if !strings.HasSuffix(attachment.Ts, "Z") {
+		t.Errorf("expected an ISO 8601 timestamp, got %q", attachment.Ts)
+	}
+}
+
+func TestRocketChatTimestamp(t *testing.T) {
+	if got := rocketChatTimestamp("1577836800"); got != "2020-01-01T00:00:00Z" {
+		t.Errorf("unexpected timestamp %q", got)
+	}
+	if got := rocketChatTimestamp("invalid"); got != "" {
+		t.Errorf("unexpected timestamp %q", got)
+	}
+}
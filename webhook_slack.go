@@ -1,6 +1,7 @@
 package slaxy
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -11,27 +12,161 @@ import (
 	"github.com/innogames/slaxy/version"
 )
 
-func (s *server) slackHandleHook(hook *webhook, channel string) error {
+func (s *server) slackHandleHook(ctx context.Context, hook *Webhook, channel string, r *route) error {
 	if s.slack == nil {
 		return nil
 	}
 
-	// create message attachment
-	attachment := s.createAttachment(hook)
+	// create message attachment, linking to the tickets of the issue, blocks link to them with buttons
+	attachment := s.renderAttachment(hook, r)
+	blocks := r != nil && r.slackBlocks
+	if !blocks {
+		attachment.Fields = append(attachment.Fields, s.ticketFields(hook)...)
+	}
+
+	// look up the first message of the issue
+	messageKey := s.slackMessageKey(hook, channel)
+	var first *slackMessageRef
+	if messageKey != "" {
+		var err error
+		first, err = s.loadSlackMessage(messageKey)
+		if err != nil {
+			s.logger.Warnf("Could not load slack message of %s: %s", messageKey, err.Error())
+		}
+	}
+
+	// update the first message of the issue in place
+	if first != nil && s.cfg.SlackUpdateMessages && (hook.isResolution() || hook.isRegression()) {
+		err := s.updateSlackMessage(ctx, hook, first)
+		if err != nil {
+			return err
+		}
+
+		// the status change is visible in the updated message already
+		if !s.cfg.SlackThreads {
+			return nil
+		}
+	}
+
+	// the first alert of an issue mentions people by its level and its owners, assignments the assignee,
+	// repeated alerts would page them for every event
+	var mentions []string
+	if !hook.isNewAlert() || first == nil && s.isFirstAlert(hook, destination{kind: destinationSlack, target: channel}) {
+		if hook.isNewAlert() {
+			mentions = append(mentions, s.levelStyle(hook, r).slackMention())
+		}
+		mentions = append(mentions, s.slackMentions(ctx, hook)...)
+	}
+	mention := joinMentions(mentions...)
+	options := s.slackMessageOptions(hook, attachment, blocks, mention)
+
+	// reply in the thread of the first message of the issue
+	if first != nil && s.cfg.SlackThreads {
+		options = append(options, slack.MsgOptionTS(first.Timestamp))
+		if s.cfg.SlackThreadBroadcast {
+			options = append(options, slack.MsgOptionBroadcast())
+		}
+	}
 
 	// post the message
 	s.logger.Debugf("begin post message to slack, channel=%v attachment=%v", channel, attachment)
-	channelID, timestamp, err := s.slack.PostMessage(channel, slack.MsgOptionAttachments(attachment))
+	channelID, timestamp, err := s.slack.PostMessageContext(ctx, channel, options...)
 	if err != nil {
 		return fmt.Errorf("error while posting message: %w", err)
 	}
 
+	if messageKey != "" && first == nil {
+		err = s.saveSlackMessage(messageKey, &slackMessageRef{Channel: channelID, Timestamp: timestamp, Attachment: &attachment, Blocks: blocks})
+		if err != nil {
+			s.logger.Warnf("Could not save slack message of %s: %s", messageKey, err.Error())
+		}
+	}
+
 	s.logger.Infof("Message successfully sent to channel %s (%s) at %s", channelID, channel, timestamp)
 	return nil
 }
 
+// updateSlackMessage updates the first message of the issue with its resolution or regression
+func (s *server) updateSlackMessage(ctx context.Context, hook *Webhook, first *slackMessageRef) error {
+	if first.Attachment == nil {
+		return nil
+	}
+
+	attachment := s.createUpdatedAttachment(*first.Attachment, hook)
+	options := s.slackMessageOptions(hook, attachment, first.Blocks, "")
+	_, _, _, err := s.slack.UpdateMessageContext(ctx, first.Channel, first.Timestamp, options...)
+	if err != nil {
+		return fmt.Errorf("error while updating message: %w", err)
+	}
+
+	s.logger.Infof("Message successfully updated in channel %s at %s", first.Channel, first.Timestamp)
+	return nil
+}
+
+// slackMessageRef references a posted slack message
+type slackMessageRef struct {
+	Channel    string            `json:"channel"`
+	Timestamp  string            `json:"ts"`
+	Attachment *slack.Attachment `json:"attachment,omitempty"`
+	// Blocks is set if the message was posted as blocks rendered from the attachment
+	Blocks bool `json:"blocks,omitempty"`
+}
+
+// slackMessageKey returns the store key of the first message of the hook's issue in the channel,
+// it is empty if neither threads nor updates are enabled or the hook does not belong to an issue
+func (s *server) slackMessageKey(hook *Webhook, channel string) string {
+	if !s.cfg.SlackThreads && !s.cfg.SlackUpdateMessages {
+		return ""
+	}
+
+	key := hook.issueKey()
+	if key == "" {
+		return ""
+	}
+
+	return "slack:" + channel + ":" + key
+}
+
+// loadSlackMessage returns the stored slack message or nil if there is none
+func (s *server) loadSlackMessage(key string) (*slackMessageRef, error) {
+	value, ok, err := s.store.Get(key)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	ref := &slackMessageRef{}
+	if err := json.Unmarshal([]byte(value), ref); err != nil {
+		return nil, err
+	}
+
+	return ref, nil
+}
+
+// saveSlackMessage stores the slack message
+func (s *server) saveSlackMessage(key string, ref *slackMessageRef) error {
+	value, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Set(key, string(value), s.cfg.StoreTTL)
+}
+
 // createAttachment will create the slack message attachment
-func (s *server) createAttachment(hook *webhook) slack.Attachment {
+func (s *server) createAttachment(hook *Webhook) slack.Attachment {
+	if hook.Resource == resourceSummary {
+		return slack.Attachment{
+			Text:  hook.Message,
+			Color: colorIgnored,
+		}
+	}
+	if hook.MetricAlert != nil {
+		return s.createMetricAlertAttachment(hook)
+	}
+	if hook.isStatusChange() {
+		return s.createStatusChangeAttachment(hook)
+	}
+
 	// default fields
 	fields := []slack.AttachmentField{
 		{
@@ -82,15 +217,32 @@ func (s *server) createAttachment(hook *webhook) slack.Attachment {
 		})
 	}
 
-	if len(hook.Event.Exception.Values) > 0 && len(hook.Event.Exception.Values[0].Stacktrace.Frames) > 0 {
-		frameLen := len(hook.Event.Exception.Values[0].Stacktrace.Frames)
+	if stacktrace := s.renderStacktrace(hook, markdownStacktrace, slackStacktraceMax); stacktrace != "" {
 		fields = append(fields, slack.AttachmentField{
 			Title: "Stacktrace",
-			Value: hook.Event.Exception.Values[0].Stacktrace.Frames[frameLen-1].String(),
+			Value: stacktrace,
 		})
 	}
 
 	// put all sentry tags as attachment fields
+	fields = append(fields, s.tagFields(hook)...)
+
+	return slack.Attachment{
+		Title:     hook.Title(),
+		TitleLink: hook.URL,
+		// Text:   fmt.Sprintf("<%s|*%s*>", html.EscapeString(hook.URL), html.EscapeString(title)),
+		Color:  s.levelStyle(hook, nil).Color,
+		Fields: fields,
+		Footer: "Slaxy v" + version.Version,
+		// icon from https://github.com/getsentry
+		FooterIcon: "https://avatars.githubusercontent.com/u/1396951?s=200&v=4",
+		Ts:         json.Number(fmt.Sprint(time.Now().Unix())),
+	}
+}
+
+// tagFields returns the sentry tags as attachment fields, without the default fields and user-excluded tags
+func (s *server) tagFields(hook *Webhook) []slack.AttachmentField {
+	var fields []slack.AttachmentField
 	for _, tag := range hook.Event.Tags {
 		tagKey := tag[0]
 		tagValue := tag[1]
@@ -113,31 +265,123 @@ func (s *server) createAttachment(hook *webhook) slack.Attachment {
 		})
 	}
 
-	var title string
-	// message is empty most of the time
-	if hook.Message != "" {
-		lines := strings.Split(hook.Message, "\n")
-		title = lines[0]
+	return fields
+}
+
+// createMetricAlertAttachment will create the slack message attachment of a metric alert
+func (s *server) createMetricAlertAttachment(hook *Webhook) slack.Attachment {
+	alert := hook.MetricAlert
+	fields := []slack.AttachmentField{
+		{
+			Title: "Alert Rule",
+			Value: alert.MetricAlert.AlertRule.Name,
+			Short: true,
+		},
+		{
+			Title: "State",
+			Value: alert.state(hook.Action),
+			Short: true,
+		},
+	}
+
+	if threshold := alert.threshold(hook.Action); threshold != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Threshold",
+			Value: threshold,
+			Short: true,
+		})
+	}
+
+	if value := alert.currentValue(); value != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Current Value",
+			Value: value,
+			Short: true,
+		})
 	}
 
-	if title == "" {
-		// fallback to event.title
-		title = fmt.Sprintf("[%s] %s", hook.Event.Location, hook.Event.Title)
+	if hook.ProjectName != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Project",
+			Value: hook.ProjectName,
+			Short: true,
+		})
+	}
+
+	if hook.Event.Environment != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Environment",
+			Value: hook.Event.Environment,
+			Short: true,
+		})
 	}
 
 	return slack.Attachment{
-		Title:     title,
-		TitleLink: hook.URL,
-		// Text:   fmt.Sprintf("<%s|*%s*>", html.EscapeString(hook.URL), html.EscapeString(title)),
-		Color:  "#f43f20",
-		Fields: fields,
-		Footer: "Slaxy v" + version.Version,
-		// icon from https://github.com/getsentry
+		Title:      hook.Title(),
+		TitleLink:  hook.URL,
+		Text:       alert.details(),
+		Color:      alert.color(hook.Action),
+		Fields:     fields,
+		Footer:     "Slaxy v" + version.Version,
 		FooterIcon: "https://avatars.githubusercontent.com/u/1396951?s=200&v=4",
 		Ts:         json.Number(fmt.Sprint(time.Now().Unix())),
 	}
 }
 
+// createStatusChangeAttachment will create a compact slack message attachment of an issue lifecycle change
+func (s *server) createStatusChangeAttachment(hook *Webhook) slack.Attachment {
+	title := hook.Title()
+	if hook.Issue.ShortID != "" {
+		title = hook.Issue.ShortID + ": " + title
+	}
+
+	return slack.Attachment{
+		Title:      title,
+		TitleLink:  hook.URL,
+		Text:       hook.statusChange(),
+		Color:      hook.statusColor(),
+		Footer:     "Slaxy v" + version.Version,
+		FooterIcon: "https://avatars.githubusercontent.com/u/1396951?s=200&v=4",
+		Ts:         json.Number(fmt.Sprint(time.Now().Unix())),
+	}
+}
+
+// createUpdatedAttachment will mark the original attachment as resolved or regressed
+func (s *server) createUpdatedAttachment(original slack.Attachment, hook *Webhook) slack.Attachment {
+	attachment := original
+	attachment.Fields = append([]slack.AttachmentField(nil), original.Fields...)
+
+	status := "🔁 " + hook.statusChange()
+	attachment.Color = colorCritical
+	if hook.isResolution() {
+		status = "✅ " + hook.statusChange()
+		attachment.Color = colorResolved
+
+		if hook.Actor.Name != "" {
+			attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+				Title: "Resolved By",
+				Value: hook.Actor.Name,
+				Short: true,
+			})
+		}
+
+		if hook.Issue != nil && hook.Issue.StatusDetails.InRelease != "" {
+			attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+				Title: "Resolved In Release",
+				Value: hook.Issue.StatusDetails.InRelease,
+				Short: true,
+			})
+		}
+	}
+
+	attachment.Text = status
+	if original.Text != "" {
+		attachment.Text += "\n" + original.Text
+	}
+
+	return attachment
+}
+
 // isExcluded checks whether str should be excluded
 func (s *server) isExcluded(str string) bool {
 	for _, regex := range s.excludedFields {
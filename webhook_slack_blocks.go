@@ -0,0 +1,149 @@
+package slaxy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// slack formats of a route
+const (
+	slackFormatAttachments = "attachments"
+	slackFormatBlocks      = "blocks"
+)
+
+// block kit limits, see https://api.slack.com/reference/block-kit/blocks
+const (
+	slackHeaderMax     = 150
+	slackSectionMax    = 3000
+	slackFieldMax      = 2000
+	slackSectionFields = 10
+	slackButtonMax     = 75
+	slackBlocksMax     = 50
+)
+
+// slackMessageOptions returns the content of the message, the legacy attachment or the blocks of it,
+// the mention like "<!here>" is the text of the message
+func (s *server) slackMessageOptions(hook *Webhook, attachment slack.Attachment, blocks bool, mention string) []slack.MsgOption {
+	if !blocks {
+		options := []slack.MsgOption{slack.MsgOptionAttachments(attachment)}
+		if mention != "" {
+			options = append(options, slack.MsgOptionText(mention, false))
+		}
+		return options
+	}
+
+	text := attachmentFallback(attachment)
+	if mention != "" {
+		text = mention + " " + text
+	}
+
+	return []slack.MsgOption{
+		// the text is shown in notifications only
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(slackBlocks(attachment, s.tickets(hook), mention)...),
+	}
+}
+
+// slackBlocks will create the block kit layout of the attachment: the mention, a header, a context with the footer,
+// sections of the text and fields, the stacktrace in a code block and buttons to sentry and the tickets
+func slackBlocks(attachment slack.Attachment, tickets []ticket, mention string) []slack.Block {
+	var blocks []slack.Block
+	if mention != "" {
+		blocks = append(blocks, slackSection(mention))
+	}
+	if attachment.Title != "" {
+		blocks = append(blocks, slack.NewHeaderBlock(
+			slack.NewTextBlockObject(slack.PlainTextType, truncate(attachment.Title, slackHeaderMax), true, false),
+		))
+	}
+
+	if footer := slackContext(attachment); footer != nil {
+		blocks = append(blocks, footer)
+	}
+
+	if attachment.Text != "" {
+		blocks = append(blocks, slackSection(attachment.Text))
+	}
+
+	// short fields side by side, long fields like the stacktrace in sections of their own
+	var short []*slack.TextBlockObject
+	var long []slack.Block
+	for _, field := range attachment.Fields {
+		if field.Value == "" {
+			continue
+		}
+		if field.Short {
+			text := truncateMrkdwn(fmt.Sprintf("*%s*\n%s", field.Title, field.Value), slackFieldMax)
+			short = append(short, slack.NewTextBlockObject(slack.MarkdownType, text, false, false))
+			continue
+		}
+		long = append(long, slackSection(fmt.Sprintf("*%s*\n%s", field.Title, field.Value)))
+	}
+	for len(short) > 0 {
+		n := len(short)
+		if n > slackSectionFields {
+			n = slackSectionFields
+		}
+		blocks = append(blocks, slack.NewSectionBlock(nil, short[:n], nil))
+		short = short[n:]
+	}
+	blocks = append(blocks, long...)
+
+	var buttons []slack.BlockElement
+	if attachment.TitleLink != "" {
+		buttons = append(buttons, slack.NewButtonBlockElement("sentry", "sentry",
+			slack.NewTextBlockObject(slack.PlainTextType, "Open in Sentry", false, false),
+		).WithURL(attachment.TitleLink).WithStyle(slack.StylePrimary))
+	}
+	for i, t := range tickets {
+		buttons = append(buttons, slack.NewButtonBlockElement(fmt.Sprintf("ticket-%d", i), t.Key,
+			slack.NewTextBlockObject(slack.PlainTextType, truncate("Open "+t.Key, slackButtonMax), false, false),
+		).WithURL(t.URL))
+	}
+
+	// keep the buttons if there are too many fields
+	max := slackBlocksMax
+	if len(buttons) > 0 {
+		max--
+	}
+	if len(blocks) > max {
+		blocks = blocks[:max]
+	}
+	if len(buttons) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("actions", buttons...))
+	}
+
+	return blocks
+}
+
+// slackContext returns the context block of the footer and the time of the attachment, nil without both
+func slackContext(attachment slack.Attachment) *slack.ContextBlock {
+	var elements []slack.MixedElement
+	if attachment.FooterIcon != "" {
+		elements = append(elements, slack.NewImageBlockElement(attachment.FooterIcon, "sentry"))
+	}
+	if attachment.Footer != "" {
+		elements = append(elements, slack.NewTextBlockObject(slack.MarkdownType, attachment.Footer, false, false))
+	}
+	if ts, err := attachment.Ts.Int64(); err == nil && ts > 0 {
+		// slack shows the time in the timezone of the reader
+		fallback := time.Unix(ts, 0).UTC().Format(time.RFC3339)
+		elements = append(elements, slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", ts, fallback), false, false))
+	}
+	if len(elements) == 0 {
+		return nil
+	}
+
+	return slack.NewContextBlock("", elements...)
+}
+
+// slackSection returns a section of the mrkdwn text
+func slackSection(text string) *slack.SectionBlock {
+	return slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, truncateMrkdwn(text, slackSectionMax), false, false),
+		nil, nil,
+	)
+}
@@ -0,0 +1,129 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSlackBlocks(t *testing.T) {
+	fake := newFakeSlack(t)
+	s := New(Config{SlackUpdateMessages: true}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	r, err := compileRoute(&Route{Destinations: []string{"slack"}, SlackFormat: "blocks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := parseWebhook(resourceIssue, []byte(`{"action": "created", "data": {"issue": {"id": "1", "title": "boom",
+		"web_url": "https://sentry.io/organizations/acme/issues/1/"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created.ProjectName = "backend"
+	created.Event.Exception.Values = []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+		{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+	}}}}
+	resolved, err := parseWebhook(resourceIssue, []byte(`{"action": "resolved", "data": {"issue": {"id": "1", "title": "boom"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.saveTicket(created, ticket{Destination: "jira:backend", Key: "OPS-7", URL: "https://acme.atlassian.net/browse/OPS-7"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hook := range []*Webhook{created, resolved} {
+		if err := s.slackHandleHook(context.Background(), hook, "alerts", r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	post := fake.call(0)
+	if post.Form.Get("attachments") != "" || post.Form.Get("text") != created.Title() {
+		t.Errorf("expected blocks with a notification text, got %v", post.Form)
+	}
+
+	blocks := slack.Blocks{}
+	if err := json.Unmarshal([]byte(post.Form.Get("blocks")), &blocks); err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, block := range blocks.BlockSet {
+		types = append(types, string(block.BlockType()))
+	}
+	if got := strings.Join(types, ","); got != "header,context,section,section,actions" {
+		t.Fatalf("unexpected blocks %s", got)
+	}
+
+	stacktrace := blocks.BlockSet[3].(*slack.SectionBlock)
+	if !strings.Contains(stacktrace.Text.Text, "```\npanic(err)\n```") {
+		t.Errorf("expected the stacktrace in a code block, got %q", stacktrace.Text.Text)
+	}
+
+	actions := blocks.BlockSet[4].(*slack.ActionBlock)
+	if len(actions.Elements.ElementSet) != 2 {
+		t.Fatalf("expected buttons to sentry and the ticket, got %+v", actions.Elements.ElementSet)
+	}
+	if button := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement); button.URL != created.URL {
+		t.Errorf("unexpected sentry button %+v", button)
+	}
+	if button := actions.Elements.ElementSet[1].(*slack.ButtonBlockElement); button.Text.Text != "Open OPS-7" {
+		t.Errorf("unexpected ticket button %+v", button)
+	}
+
+	// the resolution updates the blocks of the first message
+	update := fake.call(1)
+	if update.Method != "chat.update" || update.Form.Get("attachments") != "" {
+		t.Fatalf("expected an update of the blocks, got %s %v", update.Method, update.Form)
+	}
+	if !strings.Contains(update.Form.Get("blocks"), "✅ Resolved") {
+		t.Errorf("expected the resolution in the blocks, got %s", update.Form.Get("blocks"))
+	}
+}
+
+func TestSlackBlocksLimits(t *testing.T) {
+	attachment := slack.Attachment{
+		Title:     strings.Repeat("a", 200),
+		TitleLink: "https://sentry.io/organizations/acme/issues/1/",
+		Text:      "```\n" + strings.Repeat("x\n", 2000) + "```",
+	}
+	for i := 0; i < 12; i++ {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{Title: fmt.Sprint(i), Value: "v", Short: true})
+	}
+	for i := 0; i < 60; i++ {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{Title: fmt.Sprint(i), Value: "long"})
+	}
+
+	blocks := slackBlocks(attachment, nil, "")
+	if len(blocks) != slackBlocksMax {
+		t.Fatalf("expected %d blocks, got %d", slackBlocksMax, len(blocks))
+	}
+	if _, ok := blocks[len(blocks)-1].(*slack.ActionBlock); !ok {
+		t.Errorf("the buttons should be kept, got %T", blocks[len(blocks)-1])
+	}
+
+	header := blocks[0].(*slack.HeaderBlock)
+	if n := len([]rune(header.Text.Text)); n != slackHeaderMax {
+		t.Errorf("expected the header to be cut to %d characters, got %d", slackHeaderMax, n)
+	}
+
+	text := blocks[1].(*slack.SectionBlock).Text.Text
+	if len([]rune(text)) > slackSectionMax || !strings.HasSuffix(text, "\n```") {
+		t.Errorf("expected the text to be cut with a closed code block, got %d characters", len([]rune(text)))
+	}
+
+	fields := blocks[2].(*slack.SectionBlock)
+	if len(fields.Fields) != slackSectionFields || len(blocks[3].(*slack.SectionBlock).Fields) != 2 {
+		t.Errorf("expected the short fields in sections of %d", slackSectionFields)
+	}
+
+	if _, err := compileRoute(&Route{Destinations: []string{"slack"}, SlackFormat: "cards"}); err == nil {
+		t.Error("expected an error of the unknown slack format")
+	}
+}
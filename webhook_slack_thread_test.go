@@ -0,0 +1,171 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeSlack records the calls of the slack web api
+type fakeSlack struct {
+	*httptest.Server
+	mu    sync.Mutex
+	calls []fakeSlackCall
+	// responses are the bodies of the methods which don't post messages
+	responses map[string]string
+}
+
+type fakeSlackCall struct {
+	Method string
+	Form   url.Values
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	f := &fakeSlack{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+
+		f.mu.Lock()
+		f.calls = append(f.calls, fakeSlackCall{Method: r.URL.Path[1:], Form: r.PostForm})
+		ts := fmt.Sprintf("1645672116.%06d", len(f.calls))
+		response, ok := f.responses[r.URL.Path[1:]]
+		f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if ok {
+			fmt.Fprint(w, response)
+			return
+		}
+		fmt.Fprintf(w, `{"ok": true, "channel": "C0123", "ts": %q}`, ts)
+	}))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+// client returns a slack client talking to the fake
+func (f *fakeSlack) client() *slack.Client {
+	return slack.New("xoxb-test", slack.OptionAPIURL(f.URL+"/"))
+}
+
+func (f *fakeSlack) call(i int) fakeSlackCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[i]
+}
+
+func TestSlackThreads(t *testing.T) {
+	fake := newFakeSlack(t)
+	s := New(Config{SlackThreads: true, SlackThreadBroadcast: true}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	hook := &Webhook{ID: "1170820242", ProjectName: "backend", Event: SentryEvent{Title: "boom"}}
+	for i := 0; i < 2; i++ {
+		if err := s.slackHandleHook(context.Background(), hook, "alerts", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := &Webhook{ID: "42", ProjectName: "backend", Event: SentryEvent{Title: "bang"}}
+	if err := s.slackHandleHook(context.Background(), other, "alerts", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if first := fake.call(0); first.Form.Get("thread_ts") != "" {
+		t.Errorf("first message should not be a reply, got thread_ts=%s", first.Form.Get("thread_ts"))
+	}
+
+	reply := fake.call(1)
+	if reply.Form.Get("thread_ts") != "1645672116.000001" {
+		t.Errorf("follow-up should reply to the first message, got thread_ts=%s", reply.Form.Get("thread_ts"))
+	}
+	if reply.Form.Get("reply_broadcast") != "true" {
+		t.Error("follow-up should be broadcast to the channel")
+	}
+
+	if third := fake.call(2); third.Form.Get("thread_ts") != "" {
+		t.Errorf("another issue should not be a reply, got thread_ts=%s", third.Form.Get("thread_ts"))
+	}
+}
+
+func TestSlackThreadsDisabled(t *testing.T) {
+	fake := newFakeSlack(t)
+	s := New(Config{}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	hook := &Webhook{ID: "1170820242", Event: SentryEvent{Title: "boom"}}
+	for i := 0; i < 2; i++ {
+		if err := s.slackHandleHook(context.Background(), hook, "alerts", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if reply := fake.call(1); reply.Form.Get("thread_ts") != "" {
+		t.Errorf("threads are disabled, got thread_ts=%s", reply.Form.Get("thread_ts"))
+	}
+}
+
+func TestSlackUpdateMessageOnResolution(t *testing.T) {
+	fake := newFakeSlack(t)
+	s := New(Config{SlackUpdateMessages: true}, NewNullLogger()).(*server)
+	s.slack = fake.client()
+
+	created, err := parseWebhook(resourceIssue, []byte(`{"action": "created", "data": {"issue": {"id": "1", "title": "boom"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := parseWebhook(resourceIssue, []byte(`{"action": "resolved", "actor": {"type": "user", "name": "Jane"},
+		"data": {"issue": {"id": "1", "title": "boom", "statusDetails": {"inRelease": "v1.2.0"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hook := range []*Webhook{created, resolved} {
+		if err := s.slackHandleHook(context.Background(), hook, "alerts", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake.mu.Lock()
+	calls := len(fake.calls)
+	fake.mu.Unlock()
+	if calls != 2 {
+		t.Fatalf("expected a post and an update, got %d calls", calls)
+	}
+
+	update := fake.call(1)
+	if update.Method != "chat.update" || update.Form.Get("ts") != "1645672116.000001" {
+		t.Fatalf("expected an update of the first message, got %s ts=%s", update.Method, update.Form.Get("ts"))
+	}
+
+	var attachments []slack.Attachment
+	if err := json.Unmarshal([]byte(update.Form.Get("attachments")), &attachments); err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 1 || attachments[0].Color != colorResolved {
+		t.Fatalf("unexpected attachments %+v", attachments)
+	}
+	if !strings.HasPrefix(attachments[0].Text, "✅ Resolved in release v1.2.0 by Jane") {
+		t.Errorf("unexpected status line %q", attachments[0].Text)
+	}
+
+	var resolvedIn string
+	for _, field := range attachments[0].Fields {
+		if field.Title == "Resolved In Release" {
+			resolvedIn = field.Value
+		}
+	}
+	if resolvedIn != "v1.2.0" {
+		t.Errorf("expected a resolved in release field, got %+v", attachments[0].Fields)
+	}
+}
@@ -0,0 +1,214 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// teamsMessage is a message with adaptive card attachments for teams incoming webhooks and workflows
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string            `json:"contentType"`
+	Content     teamsAdaptiveCard `json:"content"`
+}
+
+type teamsAdaptiveCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	Actions []teamsAction  `json:"actions,omitempty"`
+	MSTeams struct {
+		Width string `json:"width"`
+	} `json:"msteams"`
+}
+
+// teamsElement is a TextBlock or FactSet element of an adaptive card
+type teamsElement struct {
+	Type     string      `json:"type"`
+	Text     string      `json:"text,omitempty"`
+	Weight   string      `json:"weight,omitempty"`
+	Size     string      `json:"size,omitempty"`
+	Color    string      `json:"color,omitempty"`
+	FontType string      `json:"fontType,omitempty"`
+	IsSubtle bool        `json:"isSubtle,omitempty"`
+	Wrap     bool        `json:"wrap,omitempty"`
+	Facts    []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func (s *server) teamsHandleHook(ctx context.Context, hook *Webhook, name string) error {
+	url, err := s.teamsWebhookURL(name)
+	if err != nil {
+		return err
+	}
+
+	message := s.createTeamsMessage(hook)
+	res, err := s.client.R().SetContext(ctx).SetBody(message).Post(url)
+	if err != nil {
+		return fmt.Errorf("failed to send teams message, err=%w", err)
+	}
+	if res.StatusCode() >= 300 {
+		return fmt.Errorf("failed to send teams message, response_body=%s", res.Body())
+	}
+
+	return nil
+}
+
+// teamsWebhookURL returns the url of the named teams webhook
+func (s *server) teamsWebhookURL(name string) (string, error) {
+	if url, ok := lookupFold(s.cfg.TeamsWebhooks, name); ok {
+		return url, nil
+	}
+
+	return "", fmt.Errorf("unknown teams webhook %q", name)
+}
+
+// checkTeamsWebhook checks the url of the teams webhook, it can't be requested without posting
+func checkTeamsWebhook(webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url, err: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid webhook url %q", webhook)
+	}
+
+	return nil
+}
+
+// createTeamsMessage will create the adaptive card of the same fields as the slack attachment
+func (s *server) createTeamsMessage(hook *Webhook) teamsMessage {
+	attachment := s.createAttachment(hook)
+
+	card := teamsAdaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+	}
+	card.MSTeams.Width = "Full"
+
+	if attachment.Title != "" {
+		card.Body = append(card.Body, teamsElement{
+			Type:   "TextBlock",
+			Text:   attachment.Title,
+			Weight: "Bolder",
+			Size:   "Medium",
+			Color:  teamsColor(attachment.Color),
+			Wrap:   true,
+		})
+	}
+
+	card.Body = append(card.Body, teamsTextBlocks(attachment.Text)...)
+
+	// short fields are facts, long ones like the stacktrace get their own blocks
+	var facts []teamsFact
+	var long []slack.AttachmentField
+	for _, field := range attachment.Fields {
+		if field.Short {
+			facts = append(facts, teamsFact{Title: field.Title, Value: mrkdwnToTeams(field.Value)})
+			continue
+		}
+		long = append(long, field)
+	}
+
+	if len(facts) > 0 {
+		card.Body = append(card.Body, teamsElement{Type: "FactSet", Facts: facts})
+	}
+
+	for _, field := range long {
+		card.Body = append(card.Body, teamsElement{
+			Type:   "TextBlock",
+			Text:   field.Title,
+			Weight: "Bolder",
+			Wrap:   true,
+		})
+		card.Body = append(card.Body, teamsTextBlocks(field.Value)...)
+	}
+
+	if attachment.Footer != "" {
+		card.Body = append(card.Body, teamsElement{
+			Type:     "TextBlock",
+			Text:     attachment.Footer,
+			Size:     "Small",
+			IsSubtle: true,
+		})
+	}
+
+	if hook.URL != "" {
+		card.Actions = append(card.Actions, teamsAction{
+			Type:  "Action.OpenUrl",
+			Title: "Open in Sentry",
+			URL:   hook.URL,
+		})
+	}
+
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content:     card,
+			},
+		},
+	}
+}
+
+// teamsColor maps the attachment color to the closest adaptive card color
+func teamsColor(color string) string {
+	switch color {
+	case colorResolved:
+		return "Good"
+	case colorWarning:
+		return "Warning"
+	case colorAssigned, colorIgnored:
+		return "Accent"
+	default:
+		return "Attention"
+	}
+}
+
+// teamsTextBlocks converts the slack mrkdwn into text blocks, code blocks get monospace blocks of their own
+// as adaptive cards don't render code
+func teamsTextBlocks(text string) []teamsElement {
+	var blocks []teamsElement
+	// every odd part is inside a code block
+	for i, part := range strings.Split(text, "```") {
+		part = strings.Trim(part, "\n")
+		if part == "" {
+			continue
+		}
+
+		if i%2 == 1 {
+			blocks = append(blocks, teamsElement{Type: "TextBlock", Text: part, FontType: "Monospace", Wrap: true})
+			continue
+		}
+		blocks = append(blocks, teamsElement{Type: "TextBlock", Text: mrkdwnToTeams(part), Wrap: true})
+	}
+
+	return blocks
+}
+
+// mrkdwnToTeams converts slack mrkdwn into the markdown subset of adaptive cards,
+// inline code loses its backticks as they are shown as they are
+func mrkdwnToTeams(text string) string {
+	return strings.ReplaceAll(mrkdwnToMarkdown(text), "`", "")
+}
@@ -0,0 +1,103 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestTeamsHandleHook(t *testing.T) {
+	received := make(chan teamsMessage, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message teamsMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+		received <- message
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	s := New(Config{TeamsWebhooks: map[string]string{"oncall": srv.URL}}, NewNullLogger()).(*server)
+	s.excludedFields = []*regexp.Regexp{regexp.MustCompile("^sentry:.*$")}
+
+	hook := &Webhook{
+		ProjectName: "backend",
+		Culprit:     "main.run",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/1/",
+		Event: SentryEvent{
+			Title:       "boom",
+			Environment: "production",
+			Tags:        []SentryTag{{"server_name", "web-1"}, {"sentry:user", "jane"}},
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: "panic(err)"},
+			}}}}},
+		},
+	}
+	if err := s.teamsHandleHook(context.Background(), hook, "OnCall"); err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-received
+	if len(message.Attachments) != 1 || message.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("unexpected attachments %+v", message.Attachments)
+	}
+
+	card := message.Attachments[0].Content
+	if len(card.Actions) != 1 || card.Actions[0].URL != hook.URL || card.Actions[0].Title != "Open in Sentry" {
+		t.Errorf("unexpected actions %+v", card.Actions)
+	}
+
+	facts := map[string]string{}
+	var stacktrace bool
+	for _, element := range card.Body {
+		for _, fact := range element.Facts {
+			facts[fact.Title] = fact.Value
+		}
+		if element.Text == "Stacktrace" {
+			stacktrace = true
+		}
+	}
+	if facts["Project"] != "backend" || facts["Environment"] != "production" || facts["Server Name"] != "web-1" {
+		t.Errorf("unexpected facts %v", facts)
+	}
+	if _, ok := facts["Sentry:User"]; ok {
+		t.Error("excluded tag should not be rendered")
+	}
+	if !stacktrace {
+		t.Error("stacktrace should be rendered")
+	}
+
+	if err := s.teamsHandleHook(context.Background(), hook, "unknown"); err == nil {
+		t.Error("expected an error for an unknown webhook")
+	}
+}
+
+func TestTeamsTextBlocks(t *testing.T) {
+	blocks := teamsTextBlocks("*Caused by* <https://example.com|the docs> in `main.go`\n```\npanic(err)\n```")
+	if len(blocks) != 2 {
+		t.Fatalf("expected a text and a code block, got %+v", blocks)
+	}
+	if blocks[0].Text != "**Caused by** [the docs](https://example.com) in main.go" || blocks[0].FontType != "" {
+		t.Errorf("unexpected text block %+v", blocks[0])
+	}
+	if blocks[1].Text != "panic(err)" || blocks[1].FontType != "Monospace" {
+		t.Errorf("unexpected code block %+v", blocks[1])
+	}
+}
+
+func TestCheckTeamsWebhook(t *testing.T) {
+	s := New(Config{TeamsWebhooks: map[string]string{"oncall": "outlook.office.com/webhook"}}, NewNullLogger()).(*server)
+	if err := s.notifiers[destinationTeams].Check(context.Background()); err == nil {
+		t.Error("expected an error for a webhook url without scheme")
+	}
+
+	s = New(Config{TeamsWebhooks: map[string]string{"oncall": "https://acme.webhook.office.com/webhookb2/x"}}, NewNullLogger()).(*server)
+	if err := s.notifiers[destinationTeams].Check(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
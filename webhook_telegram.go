@@ -0,0 +1,376 @@
+package slaxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// telegramMaxLength is the maximum length of a telegram message text
+const telegramMaxLength = 4096
+
+// telegramAPIURL is the default telegram bot api
+const telegramAPIURL = "https://api.telegram.org"
+
+// telegramMessage is the body of the sendMessage method of the bot api
+type telegramMessage struct {
+	ChatID                string               `json:"chat_id"`
+	Text                  string               `json:"text"`
+	ParseMode             string               `json:"parse_mode"`
+	DisableWebPagePreview bool                 `json:"disable_web_page_preview"`
+	ReplyMarkup           *telegramReplyMarkup `json:"reply_markup,omitempty"`
+}
+
+type telegramReplyMarkup struct {
+	InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+}
+
+type telegramButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// telegramResponse is the response of every method of the bot api
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+func (s *server) telegramHandleHook(ctx context.Context, hook *Webhook, chat string) error {
+	chatID := s.telegramChatID(chat)
+	for _, message := range s.createTelegramMessages(hook, chatID) {
+		if err := s.telegramCall(ctx, "sendMessage", message); err != nil {
+			return fmt.Errorf("failed to send telegram message, err=%w", err)
+		}
+	}
+
+	return nil
+}
+
+// telegramCall calls the method of the bot api
+func (s *server) telegramCall(ctx context.Context, method string, body interface{}) error {
+	apiURL := s.cfg.TelegramAPIURL
+	if apiURL == "" {
+		apiURL = telegramAPIURL
+	}
+
+	var result telegramResponse
+	req := s.client.R().SetContext(ctx).SetResult(&result).SetError(&result)
+	if body != nil {
+		req.SetBody(body)
+	}
+
+	res, err := req.Post(fmt.Sprintf("%s/bot%s/%s", strings.TrimSuffix(apiURL, "/"), s.cfg.TelegramToken, method))
+	if err != nil {
+		// the url contains the token
+		return fmt.Errorf("%s failed, err: %s", method, strings.ReplaceAll(err.Error(), s.cfg.TelegramToken, "<token>"))
+	}
+	if !result.OK {
+		return fmt.Errorf("%s failed with status %d: %s", method, res.StatusCode(), result.Description)
+	}
+
+	return nil
+}
+
+// telegramChatID returns the id of the named chat, other targets are chat ids or @channelusernames
+func (s *server) telegramChatID(chat string) string {
+	if id, ok := lookupFold(s.cfg.TelegramChats, chat); ok {
+		return id
+	}
+
+	return chat
+}
+
+// createTelegramMessages will create the telegram messages of the discord message,
+// texts longer than the telegram limit are split into several messages and the last one gets the sentry link button
+func (s *server) createTelegramMessages(hook *Webhook, chatID string) []telegramMessage {
+	texts := splitTelegramText(discordToTelegram(s.createDiscordMessage(hook, telegramStacktraceMax)), telegramMaxLength)
+
+	messages := make([]telegramMessage, 0, len(texts))
+	for _, text := range texts {
+		messages = append(messages, telegramMessage{
+			ChatID:                chatID,
+			Text:                  text,
+			ParseMode:             "MarkdownV2",
+			DisableWebPagePreview: true,
+		})
+	}
+
+	if hook.URL != "" && len(messages) > 0 {
+		messages[len(messages)-1].ReplyMarkup = &telegramReplyMarkup{
+			InlineKeyboard: [][]telegramButton{{{Text: "Open in Sentry", URL: hook.URL}}},
+		}
+	}
+
+	return messages
+}
+
+// discordToTelegram converts the content and embeds of the discord message into MarkdownV2
+func discordToTelegram(message discordgo.MessageSend) string {
+	var parts []string
+	if message.Content != "" {
+		parts = append(parts, discordMarkdownToTelegram(message.Content))
+	}
+
+	for _, embed := range message.Embeds {
+		var lines []string
+		if embed.Title != "" {
+			title := "*" + escapeTelegram(embed.Title) + "*"
+			if embed.URL != "" {
+				title = fmt.Sprintf("[%s](%s)", title, escapeTelegramURL(embed.URL))
+			}
+			lines = append(lines, title)
+		}
+		if embed.Description != "" {
+			lines = append(lines, discordMarkdownToTelegram(embed.Description))
+		}
+		for _, field := range embed.Fields {
+			lines = append(lines, fmt.Sprintf("*%s*: %s", escapeTelegram(field.Name), discordMarkdownToTelegram(field.Value)))
+		}
+		if embed.Footer != nil && embed.Footer.Text != "" {
+			lines = append(lines, "_"+escapeTelegram(embed.Footer.Text)+"_")
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// discordMarkdownToTelegram converts the discord markdown we render into MarkdownV2,
+// it understands code blocks, inline code, **bold** and ### headings and escapes everything else
+func discordMarkdownToTelegram(text string) string {
+	var out strings.Builder
+	inBlock := false
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+
+		if strings.HasPrefix(line, "```") {
+			inBlock = !inBlock
+			out.WriteString("```")
+			continue
+		}
+		if inBlock {
+			out.WriteString(escapeTelegramCode(line))
+			continue
+		}
+
+		if heading := strings.TrimLeft(line, "#"); heading != line && strings.HasPrefix(heading, " ") {
+			out.WriteString("*" + escapeTelegram(strings.TrimSpace(heading)) + "*")
+			continue
+		}
+
+		// every odd part is inline code
+		for j, part := range strings.Split(line, "`") {
+			if j%2 == 1 {
+				out.WriteString("`" + escapeTelegramCode(part) + "`")
+				continue
+			}
+
+			// every odd part is bold
+			for k, text := range strings.Split(part, "**") {
+				if k%2 == 1 {
+					out.WriteString("*" + escapeTelegram(text) + "*")
+					continue
+				}
+				out.WriteString(escapeTelegram(text))
+			}
+		}
+	}
+
+	// close a code block which was left open
+	if inBlock {
+		out.WriteString("\n```")
+	}
+
+	return out.String()
+}
+
+// telegramReplacer escapes all characters which are reserved in MarkdownV2
+var telegramReplacer = strings.NewReplacer(
+	`\`, `\\`, `_`, `\_`, `*`, `\*`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`, `~`, `\~`, "`", "\\`",
+	`>`, `\>`, `#`, `\#`, `+`, `\+`, `-`, `\-`, `=`, `\=`, `|`, `\|`, `{`, `\{`, `}`, `\}`, `.`, `\.`, `!`, `\!`,
+)
+
+// escapeTelegram escapes text for MarkdownV2
+func escapeTelegram(text string) string {
+	return telegramReplacer.Replace(text)
+}
+
+// escapeTelegramCode escapes text inside code entities, only ` and \ are reserved there
+func escapeTelegramCode(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(text)
+}
+
+// escapeTelegramURL escapes the url of an inline link, only ) and \ are reserved there
+func escapeTelegramURL(url string) string {
+	return strings.NewReplacer(`\`, `\\`, `)`, `\)`).Replace(url)
+}
+
+// splitTelegramText splits the MarkdownV2 text into parts of at most max utf-16 units at line breaks,
+// code blocks are closed at the end of a part and reopened in the next one
+func splitTelegramText(text string, max int) []string {
+	const fence = "```"
+	// keep room to close an open code block
+	limit := max - len(fence) - 1
+
+	var parts, lines []string
+	length := 0
+	inBlock := false
+
+	flush := func() {
+		if inBlock {
+			lines = append(lines, fence)
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+		lines, length = nil, 0
+		if inBlock {
+			lines, length = []string{fence}, len(fence)
+		}
+	}
+
+	var add func(line string)
+	add = func(line string) {
+		n := telegramLength(line)
+		if len(lines) > 0 {
+			n++
+		}
+		// a closing fence always fits into the room kept for it
+		if len(lines) > 0 && length+n > limit && !(inBlock && line == fence) {
+			flush()
+			add(line)
+			return
+		}
+
+		lines = append(lines, line)
+		length += n
+		if strings.HasPrefix(line, fence) {
+			inBlock = !inBlock
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		// overlong lines are cut, leaving room for a reopened code block
+		for telegramLength(line) > limit-len(fence)-1 {
+			var head string
+			head, line = cutTelegramLine(line, limit-len(fence)-1)
+			add(head)
+		}
+		add(line)
+	}
+
+	if len(lines) > 0 {
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+
+	return parts
+}
+
+// cutTelegramLine cuts the line after at most n utf-16 units without splitting an escape sequence or a link,
+// the inline code, bold and italic entities open at the cut are closed and opened again in the rest
+func cutTelegramLine(line string, n int) (string, string) {
+	runes := []rune(line)
+
+	// open are the markers of the open entities, link is the part of a link the scan is in
+	var open, cutOpen []rune
+	length, cut, link := 0, 0, 0
+	for i := 0; i < len(runes); {
+		// the line can be cut before every token outside of links
+		if i > 0 && link == 0 && length+len(open) <= n {
+			cut, cutOpen = i, append(cutOpen[:0], open...)
+		}
+
+		// an escape sequence is one token
+		size := 1
+		if runes[i] == '\\' && i+1 < len(runes) {
+			size = 2
+		}
+		for _, r := range runes[i : i+size] {
+			length += utf16Length(r)
+		}
+		if length > n {
+			break
+		}
+
+		inCode := len(open) > 0 && open[len(open)-1] == '`'
+		switch r := runes[i]; {
+		case size == 2:
+		case inCode:
+			if r == '`' {
+				open = open[:len(open)-1]
+			}
+		case link == 1 && r == ']':
+			link = 0
+			if i+1 < len(runes) && runes[i+1] == '(' {
+				link = 2
+			}
+		case link == 2 && r == ')':
+			link = 0
+		case link == 0 && r == '[':
+			link = 1
+		case r == '`' || r == '*' || r == '_':
+			if j := lastRune(open, r); j >= 0 {
+				open = append(open[:j], open[j+1:]...)
+			} else {
+				open = append(open, r)
+			}
+		}
+		i += size
+	}
+
+	if cut == 0 {
+		// nothing fits around the entities, cut anywhere but in an escape sequence
+		for length := 0; cut < len(runes) && length+utf16Length(runes[cut]) <= n; cut++ {
+			length += utf16Length(runes[cut])
+		}
+		backslashes := 0
+		for i := cut - 1; i >= 0 && runes[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			cut--
+		}
+
+		return string(runes[:cut]), string(runes[cut:])
+	}
+
+	closing := make([]rune, 0, len(cutOpen))
+	for i := len(cutOpen) - 1; i >= 0; i-- {
+		closing = append(closing, cutOpen[i])
+	}
+
+	return string(runes[:cut]) + string(closing), string(cutOpen) + string(runes[cut:])
+}
+
+// lastRune returns the index of the last r in runes or -1
+func lastRune(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// telegramLength returns the length of the text in utf-16 code units, which telegram counts
+func telegramLength(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16Length(r)
+	}
+
+	return n
+}
+
+// utf16Length returns the number of utf-16 code units of the rune
+func utf16Length(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
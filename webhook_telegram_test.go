@@ -0,0 +1,167 @@
+package slaxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTelegramHandleHook(t *testing.T) {
+	var messages []telegramMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot123:abc/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		var message telegramMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, message)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok": true, "result": {}}`))
+	}))
+	defer srv.Close()
+
+	s := New(Config{
+		TelegramToken:  "123:abc",
+		TelegramAPIURL: srv.URL,
+		TelegramChats:  map[string]string{"oncall": "-100123"},
+	}, NewNullLogger()).(*server)
+
+	hook := &Webhook{
+		ProjectName: "backend-api",
+		Culprit:     "main.run",
+		Level:       "error",
+		URL:         "https://sentry.io/organizations/acme/issues/1/",
+		Event: SentryEvent{
+			Title: "index out of range [1] with length 1",
+			Exception: Exception{Values: []ExceptionValue{{Stacktrace: Stacktrace{Frames: []StacktraceFrame{
+				{Filename: "main.go", Lineno: 42, ContextLine: strings.Repeat("a := b[`i`]\n", 500)},
+			}}}}},
+		},
+	}
+	if err := s.telegramHandleHook(context.Background(), hook, "OnCall"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) < 2 {
+		t.Fatalf("expected the message to be split, got %d messages", len(messages))
+	}
+	for i, message := range messages {
+		if message.ChatID != "-100123" || message.ParseMode != "MarkdownV2" {
+			t.Errorf("unexpected message %+v", message)
+		}
+		if utf8.RuneCountInString(message.Text) > telegramMaxLength {
+			t.Errorf("message %d is too long: %d", i, utf8.RuneCountInString(message.Text))
+		}
+		if strings.Count(message.Text, "```")%2 != 0 {
+			t.Errorf("message %d has an unclosed code block", i)
+		}
+		if (message.ReplyMarkup != nil) != (i == len(messages)-1) {
+			t.Errorf("only the last message should have the button")
+		}
+	}
+
+	if !strings.HasPrefix(messages[0].Text, `[*index out of range \[1\] with length 1*](https://sentry.io/`) {
+		t.Errorf("title is not escaped: %q", messages[0].Text[:50])
+	}
+	if !strings.Contains(messages[0].Text, "\n`main.run`\n*Project*: backend\\-api\n") {
+		t.Errorf("code should not be escaped like text: %q", messages[0].Text)
+	}
+
+	button := messages[len(messages)-1].ReplyMarkup.InlineKeyboard[0][0]
+	if button.Text != "Open in Sentry" || button.URL != hook.URL {
+		t.Errorf("unexpected button %+v", button)
+	}
+}
+
+func TestTelegramHandleHookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"ok": false, "description": "Bad Request: chat not found"}`))
+	}))
+	defer srv.Close()
+
+	s := New(Config{TelegramToken: "123:abc", TelegramAPIURL: srv.URL}, NewNullLogger()).(*server)
+
+	err := s.telegramHandleHook(context.Background(), &Webhook{Event: SentryEvent{Title: "boom"}}, "@nobody")
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("expected the api error, got %v", err)
+	}
+}
+
+func TestDiscordMarkdownToTelegram(t *testing.T) {
+	tests := map[string]string{
+		"**Level** `error`":             "*Level* `error`",
+		"**Tag**: `a-b.c`":              "*Tag*: `a-b.c`",
+		"### Stacktrace":                "*Stacktrace*",
+		"1+1=2!":                        `1\+1\=2\!`,
+		"```\nfoo(`x`) \\ bar.baz\n```": "```\nfoo(\\`x\\`) \\\\ bar.baz\n```",
+	}
+
+	for in, expected := range tests {
+		if got := discordMarkdownToTelegram(in); got != expected {
+			t.Errorf("discordMarkdownToTelegram(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
+
+func TestSplitTelegramText(t *testing.T) {
+	text := "title\n```\n" + strings.Repeat("line\n", 10) + "```\nend"
+	parts := splitTelegramText(text, 30)
+
+	if len(parts) < 2 {
+		t.Fatalf("expected several parts, got %v", parts)
+	}
+	for _, part := range parts {
+		if len(part) > 30 {
+			t.Errorf("part is too long: %q", part)
+		}
+		if strings.Count(part, "```")%2 != 0 {
+			t.Errorf("part has an unclosed code block: %q", part)
+		}
+	}
+	if got := strings.Join(parts, "\n"); strings.Count(got, "line") != 10 {
+		t.Errorf("lines got lost: %q", got)
+	}
+
+	// overlong lines are cut without splitting escape sequences
+	parts = splitTelegramText(strings.Repeat(`a\.`, 20), 30)
+	for _, part := range parts {
+		if trailing := len(part) - len(strings.TrimRight(part, `\`)); trailing%2 != 0 {
+			t.Errorf("escape sequence was split: %q", part)
+		}
+	}
+}
+
+func TestCutTelegramLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		n          int
+		head, rest string
+	}{
+		// entities are closed and opened again
+		{"see `aaaa\\`aaaaaa` now", 12, "see `aaaa\\``", "`aaaaaa` now"},
+		{"*bold _italic text_ end*", 15, "*bold _italic_*", "*_ text_ end*"},
+		// links are not split
+		{"go [*Sentry*](https://sentry.io)", 20, "go ", "[*Sentry*](https://sentry.io)"},
+		// emojis are two utf-16 units
+		{"😀😀😀😀", 5, "😀😀", "😀😀"},
+	}
+
+	for _, tt := range tests {
+		head, rest := cutTelegramLine(tt.line, tt.n)
+		if head != tt.head || rest != tt.rest {
+			t.Errorf("cutTelegramLine(%q, %d) = %q, %q, expected %q, %q", tt.line, tt.n, head, rest, tt.head, tt.rest)
+		}
+		if telegramLength(head) > tt.n {
+			t.Errorf("cutTelegramLine(%q, %d) head %q is too long", tt.line, tt.n, head)
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 func TestSlackPostMessage(t *testing.T) {
-	hook := webhook{
+	hook := Webhook{
 		ProjectName:     "demo-project",
 		Message:         "",
 		ID:              "007",
@@ -19,7 +19,7 @@ func TestSlackPostMessage(t *testing.T) {
 		URL:             "https://www.google.com/",
 		Level:           "error",
 		TriggeringRules: nil,
-		Event: sentryEvent{
+		Event: SentryEvent{
 			Culprit:     "createAttachment()",
 			Title:       "<this is 'title'>",
 			EventID:     "",
@@ -29,14 +29,14 @@ func TestSlackPostMessage(t *testing.T) {
 			Location:    "webhook.go",
 			Logger:      "",
 			Type:        "",
-			Metadata:    sentryEvtMetadata{},
+			Metadata:    SentryEvtMetadata{},
 			Tags:        nil,
 			Timestamp:   float64(time.Now().Unix()),
 			Received:    float64(time.Now().Add(time.Second * 5).Unix()),
 			Level:       "error",
 			Project:     0,
 			Release:     "0.2.9",
-			User:        sentryUser{},
+			User:        SentryUser{},
 			Sdk: Sdk{
 				Version: "0.12.0",
 				Name:    "sentry-go",